@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 
 	"ai-bridges/internal/config"
 	"ai-bridges/internal/handlers"
 	"ai-bridges/internal/providers"
 	"ai-bridges/internal/providers/gemini"
+	"ai-bridges/internal/providers/geminiapi"
+	"ai-bridges/internal/providers/mock"
+	"ai-bridges/internal/providers/openaicompat"
 	"ai-bridges/internal/server"
 	"ai-bridges/pkg/logger"
 
@@ -21,31 +25,57 @@ import (
 // @description 🚀 High-performance WebAI-to-API gateway. Seamlessly bridge Google Gemini into standardized OpenAI, Anthropic (Claude), and Google Native REST APIs.
 // @host localhost:3000
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	fx.New(
 		fx.Provide(
 			config.New,
 			logger.New,
 			providers.NewProviderManager,
-			gemini.NewClient,
+			gemini.NewCredentialStore,
+			gemini.NewPool,
 			handlers.NewGeminiHandler,
 			handlers.NewOpenAIHandler,
 			handlers.NewClaudeHandler,
+			handlers.NewAdminHandler,
 		),
 		fx.Invoke(
 			server.New,
 		),
-		fx.Invoke(func(pm *providers.ProviderManager, c *gemini.Client, log *zap.Logger) {
-			pm.Register("gemini", c)
+		fx.Invoke(func(pm *providers.ProviderManager, c *gemini.Pool, cfg *config.Config, log *zap.Logger) error {
+			if len(cfg.Gemini.Accounts) > 0 {
+				pm.Register("gemini", c)
+			}
+			if cfg.GeminiAPI.APIKey != "" {
+				pm.Register(geminiapi.ProviderName, geminiapi.NewClient(cfg, log.With(zap.String("provider", geminiapi.ProviderName))))
+			}
+			for _, upstream := range cfg.OpenAI.Upstreams {
+				pm.Register(upstream.Name, openaicompat.NewClient(upstream, log.With(zap.String("provider", upstream.Name))))
+			}
+			if cfg.Mock.Enabled {
+				m, err := mock.New(cfg)
+				if err != nil {
+					return err
+				}
+				pm.Register(mock.ProviderName, m)
+				log.Warn("Mock provider enabled, its answers are canned", zap.String("mode", cfg.Mock.Mode))
+			}
 			// Initialize all providers (non-blocking, logs warnings on failure)
 			pm.InitAllProviders(context.Background())
-			// Select Gemini as the provider
-			if err := pm.SelectProvider("gemini"); err != nil {
-				log.Error("Failed to select Gemini provider", zap.Error(err))
+			// Select the first registered provider, Gemini when it has accounts
+			names := pm.ListProviders()
+			if len(names) == 0 {
+				return fmt.Errorf("no provider configured")
+			}
+			if err := pm.SelectProvider(names[0]); err != nil {
+				log.Error("Failed to select provider", zap.String("provider", names[0]), zap.Error(err))
 			} else {
-				log.Debug("Gemini provider selected")
+				log.Debug("Provider selected", zap.String("provider", names[0]))
 			}
+			return nil
 		}),
-		fx.NopLogger, 
+		fx.NopLogger,
 	).Run()
 }
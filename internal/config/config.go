@@ -2,25 +2,67 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Gemini GeminiConfig
-	Claude ClaudeConfig
-	OpenAI OpenAIConfig
-	Server ServerConfig
+	Gemini    GeminiConfig
+	GeminiAPI GeminiAPIConfig
+	Claude    ClaudeConfig
+	OpenAI    OpenAIConfig
+	Server    ServerConfig
+	Admin     AdminConfig
+	Models    ModelsConfig
+	Mock      MockConfig
 }
 
 type GeminiConfig struct {
-	Secure1PSID     string
-	Secure1PSIDTS   string
-	Secure1PSIDCC   string
+	Accounts        []GeminiAccount
 	RefreshInterval int
-	Cookies         string
+	CookieCacheDir  string // directory where each account's cookie jar is saved between runs
+	ConversationTTL int    // minutes a conversation used by the bridge may stay idle before it is deleted upstream, 0 keeps them
+
+	// CredentialStore selects how saved cookies are kept: "file", "encrypted" or "memory".
+	// The encrypted store takes its key from CredentialKey or from the file at CredentialKeyFile.
+	CredentialStore   string
+	CredentialKey     string
+	CredentialKeyFile string
+
+	Pool GeminiPoolConfig
+}
+
+// GeminiAccount holds the cookies of a single Google account
+type GeminiAccount struct {
+	Secure1PSID   string
+	Secure1PSIDTS string
+	Secure1PSIDCC string
+	Cookies       string // Cookie header, Netscape cookies.txt or exported JSON, inline or as a file path
+	Proxy         string // http://, https://, socks5:// or socks5h:// URL, optionally with user:pass
+
+	// Browser the account's traffic imitates; UserAgent overrides the profile's User-Agent
+	BrowserProfile string // "chrome", "edge", "firefox" or "safari"
+	UserAgent      string
+
+	Locale string // default language of answers as a BCP 47 tag, e.g. "en-US" or "vi"
+}
+
+// GeminiPoolConfig controls how requests are spread across accounts
+type GeminiPoolConfig struct {
+	Strategy         string // "round_robin", "least_busy" or "lowest_latency"
+	MaxAuthFailures  int
+	RecoveryInterval int // minutes between re-authentication attempts for ejected accounts
+}
+
+// GeminiAPIConfig enables the official Gemini API provider; it is disabled while APIKey is empty
+type GeminiAPIConfig struct {
+	APIKey  string
+	BaseURL string // generativelanguage REST endpoint including the API version
 }
 
 type ClaudeConfig struct {
@@ -29,19 +71,58 @@ type ClaudeConfig struct {
 	Cookies string
 }
 
+// OpenAIConfig lists the OpenAI-compatible backends requests can be routed to
 type OpenAIConfig struct {
-	APIKey  string
-	Model   string
-	Cookies string
+	Upstreams []OpenAIUpstream
+}
+
+// OpenAIUpstream is one OpenAI-compatible backend, such as vLLM, a llama.cpp server or Ollama
+type OpenAIUpstream struct {
+	Name    string // provider name routes refer to
+	BaseURL string // API root including the version, e.g. http://localhost:11434/v1
+	APIKey  string // sent as a bearer token; local servers usually need none
 }
 
 type ServerConfig struct {
 	Port string
 }
 
+// ModelsConfig points to the model routing table; the built-in table is used while RoutesFile is empty
+type ModelsConfig struct {
+	RoutesFile string
+}
+
+// MockConfig enables the built-in mock provider, which answers without any upstream
+type MockConfig struct {
+	Enabled    bool
+	Mode       string // "echo", "script" or "template"
+	ScriptFile string // JSON array of answers returned in turn by the script mode
+	Template   string // Go text/template of the answers of the template mode
+	Latency    int    // milliseconds before the first chunk
+	ChunkDelay int    // milliseconds between streamed chunks
+	ErrorEvery int    // fail every n-th request, 0 never
+	Error      string // error class of injected failures, e.g. "rate_limited"
+}
+
+// AdminConfig protects the admin API; it is disabled while Token is empty
+type AdminConfig struct {
+	Token string
+}
+
 const (
 	defaultServerPort            = "3000"
 	defaultGeminiRefreshInterval = 5
+	defaultPoolStrategy          = "least_busy"
+	defaultPoolMaxAuthFailures   = 3
+	defaultPoolRecoveryInterval  = 5
+	defaultBrowserProfile        = "chrome"
+	defaultCookieCacheDir        = ".cookies"
+	defaultCredentialStore       = "file"
+	defaultGeminiLocale          = "en-US"
+	defaultGeminiAPIBaseURL      = "https://generativelanguage.googleapis.com/v1beta"
+	defaultMockMode              = "echo"
+	defaultMockTemplate          = "Mock answer to: {{.Prompt}}"
+	defaultMockError             = "unavailable"
 )
 
 func New() (*Config, error) {
@@ -52,13 +133,37 @@ func New() (*Config, error) {
 
 	// Server
 	cfg.Server.Port = getEnv("PORT", defaultServerPort)
+	cfg.Admin.Token = os.Getenv("ADMIN_TOKEN")
+	cfg.Models.RoutesFile = os.Getenv("MODEL_ROUTES_FILE")
 
 	// Gemini
-	cfg.Gemini.Secure1PSID = os.Getenv("GEMINI_1PSID")
-	cfg.Gemini.Secure1PSIDTS = os.Getenv("GEMINI_1PSIDTS")
-	cfg.Gemini.Secure1PSIDCC = os.Getenv("GEMINI_1PSIDCC")
-	cfg.Gemini.Cookies = os.Getenv("GEMINI_COOKIES")
+	cfg.Gemini.Accounts = loadGeminiAccounts()
 	cfg.Gemini.RefreshInterval = getEnvInt("GEMINI_REFRESH_INTERVAL", defaultGeminiRefreshInterval)
+	cfg.Gemini.CookieCacheDir = getEnv("GEMINI_COOKIE_DIR", defaultCookieCacheDir)
+	cfg.Gemini.ConversationTTL = getEnvInt("GEMINI_CONVERSATION_TTL", 0)
+	cfg.Gemini.CredentialStore = getEnv("GEMINI_CREDENTIAL_STORE", defaultCredentialStore)
+	cfg.Gemini.CredentialKey = os.Getenv("GEMINI_CREDENTIAL_KEY")
+	cfg.Gemini.CredentialKeyFile = os.Getenv("GEMINI_CREDENTIAL_KEY_FILE")
+	cfg.Gemini.Pool.Strategy = getEnv("GEMINI_POOL_STRATEGY", defaultPoolStrategy)
+	cfg.Gemini.Pool.MaxAuthFailures = getEnvInt("GEMINI_POOL_MAX_AUTH_FAILURES", defaultPoolMaxAuthFailures)
+	cfg.Gemini.Pool.RecoveryInterval = getEnvInt("GEMINI_POOL_RECOVERY_INTERVAL", defaultPoolRecoveryInterval)
+
+	// OpenAI-compatible backends
+	cfg.OpenAI.Upstreams = loadOpenAIUpstreams()
+
+	// Official Gemini API
+	cfg.GeminiAPI.APIKey = os.Getenv("GEMINI_API_KEY")
+	cfg.GeminiAPI.BaseURL = getEnv("GEMINI_API_BASE_URL", defaultGeminiAPIBaseURL)
+
+	// Mock provider
+	cfg.Mock.Enabled, _ = strconv.ParseBool(os.Getenv("MOCK_ENABLED"))
+	cfg.Mock.Mode = getEnv("MOCK_MODE", defaultMockMode)
+	cfg.Mock.ScriptFile = os.Getenv("MOCK_SCRIPT_FILE")
+	cfg.Mock.Template = getEnv("MOCK_TEMPLATE", defaultMockTemplate)
+	cfg.Mock.Latency = getEnvInt("MOCK_LATENCY", 0)
+	cfg.Mock.ChunkDelay = getEnvInt("MOCK_CHUNK_DELAY", 0)
+	cfg.Mock.ErrorEvery = getEnvInt("MOCK_ERROR_EVERY", 0)
+	cfg.Mock.Error = getEnv("MOCK_ERROR", defaultMockError)
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -72,15 +177,91 @@ func New() (*Config, error) {
 func (c *Config) Validate() error {
 	var missingVars []string
 
-	// Check Gemini configuration - at least one of these should be present
-	if c.Gemini.Secure1PSID == "" {
-		missingVars = append(missingVars, "GEMINI_1PSID")
+	// Check Gemini configuration - at least one account should be present, unless the mock
+	// serves the built-in models or a routing table sends them to other providers
+	if len(c.Gemini.Accounts) == 0 && !c.Mock.Enabled {
+		if c.GeminiAPI.APIKey == "" && len(c.OpenAI.Upstreams) == 0 {
+			missingVars = append(missingVars, "GEMINI_1PSID or GEMINI_COOKIES")
+		} else if c.Models.RoutesFile == "" {
+			// The built-in routes all go to the Gemini web provider
+			missingVars = append(missingVars, "MODEL_ROUTES_FILE (or GEMINI_1PSID) when only GEMINI_API_KEY or OPENAI_BASE_URL is set")
+		}
 	}
 
-	if c.Gemini.Secure1PSID != "" {
+	for i, account := range c.Gemini.Accounts {
+		suffix := accountEnvSuffix(i)
 		// If PSID is present, we need at least one of these
-		if c.Gemini.Secure1PSIDTS == "" && c.Gemini.Secure1PSIDCC == "" && c.Gemini.Cookies == "" {
-			missingVars = append(missingVars, "GEMINI_1PSIDTS or GEMINI_1PSIDCC or GEMINI_COOKIES")
+		if account.Secure1PSIDTS == "" && account.Secure1PSIDCC == "" && account.Cookies == "" {
+			missingVars = append(missingVars, fmt.Sprintf("GEMINI_1PSIDTS%[1]s or GEMINI_1PSIDCC%[1]s or GEMINI_COOKIES%[1]s", suffix))
+		}
+		if err := validateProxy(account.Proxy); err != nil {
+			return fmt.Errorf("invalid GEMINI_PROXY%s value: %w", suffix, err)
+		}
+		switch account.BrowserProfile {
+		case "chrome", "edge", "firefox", "safari":
+		default:
+			return fmt.Errorf("invalid GEMINI_BROWSER_PROFILE%s value: %q (must be chrome, edge, firefox or safari)", suffix, account.BrowserProfile)
+		}
+		if err := ValidateLocale(account.Locale); err != nil {
+			return fmt.Errorf("invalid GEMINI_LOCALE%s value: %w", suffix, err)
+		}
+	}
+
+	switch c.Gemini.Pool.Strategy {
+	case "round_robin", "least_busy", "lowest_latency":
+	default:
+		return fmt.Errorf("invalid GEMINI_POOL_STRATEGY value: %q (must be round_robin, least_busy or lowest_latency)", c.Gemini.Pool.Strategy)
+	}
+
+	switch c.Gemini.CredentialStore {
+	case "file", "memory":
+	case "encrypted":
+		if c.Gemini.CredentialKey == "" && c.Gemini.CredentialKeyFile == "" {
+			missingVars = append(missingVars, "GEMINI_CREDENTIAL_KEY or GEMINI_CREDENTIAL_KEY_FILE")
+		}
+	default:
+		return fmt.Errorf("invalid GEMINI_CREDENTIAL_STORE value: %q (must be file, encrypted or memory)", c.Gemini.CredentialStore)
+	}
+
+	if c.Gemini.ConversationTTL < 0 {
+		return fmt.Errorf("invalid GEMINI_CONVERSATION_TTL value: %d (must be 0 or a number of minutes)", c.Gemini.ConversationTTL)
+	}
+
+	if c.GeminiAPI.APIKey != "" {
+		if u, err := url.Parse(c.GeminiAPI.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid GEMINI_API_BASE_URL value: %q (must be an http or https URL)", c.GeminiAPI.BaseURL)
+		}
+	}
+
+	names := map[string]bool{"gemini": true, "gemini-api": true, "mock": true}
+	for i, upstream := range c.OpenAI.Upstreams {
+		suffix := accountEnvSuffix(i)
+		if u, err := url.Parse(upstream.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid OPENAI_BASE_URL%s value: %q (must be an http or https URL)", suffix, upstream.BaseURL)
+		}
+		if names[upstream.Name] {
+			return fmt.Errorf("invalid OPENAI_PROVIDER_NAME%s value: %q is already taken by another provider", suffix, upstream.Name)
+		}
+		names[upstream.Name] = true
+	}
+
+	if c.Mock.Enabled {
+		switch c.Mock.Mode {
+		case "echo", "template":
+		case "script":
+			if c.Mock.ScriptFile == "" {
+				missingVars = append(missingVars, "MOCK_SCRIPT_FILE")
+			}
+		default:
+			return fmt.Errorf("invalid MOCK_MODE value: %q (must be echo, script or template)", c.Mock.Mode)
+		}
+		if c.Mock.Latency < 0 || c.Mock.ChunkDelay < 0 || c.Mock.ErrorEvery < 0 {
+			return fmt.Errorf("invalid mock configuration: MOCK_LATENCY, MOCK_CHUNK_DELAY and MOCK_ERROR_EVERY must not be negative")
+		}
+		switch c.Mock.Error {
+		case "auth_expired", "unknown_model", "rate_limited", "content_blocked", "unavailable", "parse_failure", "timeout":
+		default:
+			return fmt.Errorf("invalid MOCK_ERROR value: %q (must be auth_expired, unknown_model, rate_limited, content_blocked, unavailable, parse_failure or timeout)", c.Mock.Error)
 		}
 	}
 
@@ -100,6 +281,99 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// localeRe matches BCP 47 language tags such as "en", "en-US" or "zh-Hant-TW"
+var localeRe = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidateLocale checks that a locale is a BCP 47 language tag
+func ValidateLocale(locale string) error {
+	if !localeRe.MatchString(locale) {
+		return fmt.Errorf("%q is not a language tag like en, en-US or pt-BR", locale)
+	}
+	return nil
+}
+
+// validateProxy checks that a proxy URL uses a supported scheme and names a host
+func validateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (must be http, https, socks5 or socks5h)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", u.Redacted())
+	}
+	return nil
+}
+
+// loadGeminiAccounts reads the first account from GEMINI_1PSID, GEMINI_1PSIDTS, ...
+// and additional accounts from the same variables suffixed with _2, _3, ... until one is missing.
+// GEMINI_PROXY is the default proxy for every account; GEMINI_PROXY_<n> overrides it and
+// "direct" disables it for that account. GEMINI_BROWSER_PROFILE and GEMINI_USER_AGENT work the same way.
+func loadGeminiAccounts() []GeminiAccount {
+	defaultProxy := os.Getenv("GEMINI_PROXY")
+	defaultProfile := getEnv("GEMINI_BROWSER_PROFILE", defaultBrowserProfile)
+	defaultUserAgent := os.Getenv("GEMINI_USER_AGENT")
+	defaultLocale := getEnv("GEMINI_LOCALE", defaultGeminiLocale)
+
+	var accounts []GeminiAccount
+	for i := 0; ; i++ {
+		suffix := accountEnvSuffix(i)
+		account := GeminiAccount{
+			Secure1PSID:   os.Getenv("GEMINI_1PSID" + suffix),
+			Secure1PSIDTS: os.Getenv("GEMINI_1PSIDTS" + suffix),
+			Secure1PSIDCC: os.Getenv("GEMINI_1PSIDCC" + suffix),
+			Cookies:       os.Getenv("GEMINI_COOKIES" + suffix),
+			Proxy:         getEnv("GEMINI_PROXY"+suffix, defaultProxy),
+
+			BrowserProfile: getEnv("GEMINI_BROWSER_PROFILE"+suffix, defaultProfile),
+			UserAgent:      getEnv("GEMINI_USER_AGENT"+suffix, defaultUserAgent),
+			Locale:         getEnv("GEMINI_LOCALE"+suffix, defaultLocale),
+		}
+		if account.Proxy == "direct" {
+			account.Proxy = ""
+		}
+		// GEMINI_COOKIES alone is enough, the PSID is taken from it
+		if account.Secure1PSID == "" && account.Cookies == "" {
+			return accounts
+		}
+		accounts = append(accounts, account)
+	}
+}
+
+// loadOpenAIUpstreams reads the first OpenAI-compatible backend from OPENAI_BASE_URL,
+// OPENAI_API_KEY and OPENAI_PROVIDER_NAME, and more backends from the same variables suffixed
+// with _2, _3, ... until a base URL is missing. Backends are named "openai", "openai-2", ... by default.
+func loadOpenAIUpstreams() []OpenAIUpstream {
+	var upstreams []OpenAIUpstream
+	for i := 0; ; i++ {
+		suffix := accountEnvSuffix(i)
+		upstream := OpenAIUpstream{
+			Name:    getEnv("OPENAI_PROVIDER_NAME"+suffix, "openai"+strings.ReplaceAll(suffix, "_", "-")),
+			BaseURL: os.Getenv("OPENAI_BASE_URL" + suffix),
+			APIKey:  os.Getenv("OPENAI_API_KEY" + suffix),
+		}
+		if upstream.BaseURL == "" {
+			return upstreams
+		}
+		upstreams = append(upstreams, upstream)
+	}
+}
+
+// accountEnvSuffix returns the environment variable suffix for the account at index i
+func accountEnvSuffix(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return "_" + strconv.Itoa(i+1)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -118,5 +392,3 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return value
 }
-
-
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"ai-bridges/internal/handlers"
+)
+
+// AdminController registers the admin endpoints and contains Swagger annotations.
+// Every route requires the ADMIN_TOKEN as a bearer token.
+type AdminController struct {
+	handler *handlers.AdminHandler
+}
+
+func NewAdminController(h *handlers.AdminHandler) *AdminController {
+	return &AdminController{handler: h}
+}
+
+// HandleAccounts returns the credential status of every account
+// @Summary Account status
+// @Description Returns cookie age, last and next rotation and the last authentication error of every Gemini account
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AccountStatusResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /admin/accounts [get]
+func (a *AdminController) HandleAccounts(ctx *fiber.Ctx) error {
+	return a.handler.HandleAccounts(ctx)
+}
+
+// HandleUpdateCookies replaces the cookies of an account
+// @Summary Update account cookies
+// @Description Signs an account in with new cookies without restarting or interrupting requests in flight
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param request body models.UpdateCookiesRequest true "New cookies"
+// @Success 200 {object} models.AccountStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/accounts/{id}/cookies [put]
+func (a *AdminController) HandleUpdateCookies(ctx *fiber.Ctx) error {
+	return a.handler.HandleUpdateCookies(ctx)
+}
+
+// HandleRotate forces a cookie rotation
+// @Summary Rotate account cookies
+// @Description Rotates the __Secure-1PSIDTS cookie of an account right away
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Success 200 {object} models.AccountStatusResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/accounts/{id}/rotate [post]
+func (a *AdminController) HandleRotate(ctx *fiber.Ctx) error {
+	return a.handler.HandleRotate(ctx)
+}
+
+// HandleConversations lists the recent conversations of an account
+// @Summary List conversations
+// @Description Returns the most recent conversations in the Gemini history of an account, newest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param limit query int false "Maximum number of conversations" default(20)
+// @Success 200 {object} models.ConversationListResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/accounts/{id}/conversations [get]
+func (a *AdminController) HandleConversations(ctx *fiber.Ctx) error {
+	return a.handler.HandleConversations(ctx)
+}
+
+// HandleConversation returns the turns of a conversation
+// @Summary Read conversation
+// @Description Returns the prompts and answers of a conversation, oldest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param cid path string true "Conversation ID"
+// @Param limit query int false "Maximum number of turns" default(20)
+// @Success 200 {object} models.ConversationResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/accounts/{id}/conversations/{cid} [get]
+func (a *AdminController) HandleConversation(ctx *fiber.Ctx) error {
+	return a.handler.HandleConversation(ctx)
+}
+
+// HandleDeleteConversation deletes a conversation
+// @Summary Delete conversation
+// @Description Removes a conversation from the Gemini history of an account
+// @Tags Admin
+// @Security BearerAuth
+// @Param id path int true "Account ID"
+// @Param cid path string true "Conversation ID"
+// @Success 204
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/accounts/{id}/conversations/{cid} [delete]
+func (a *AdminController) HandleDeleteConversation(ctx *fiber.Ctx) error {
+	return a.handler.HandleDeleteConversation(ctx)
+}
+
+// Register registers the admin routes onto the provided group
+func (a *AdminController) Register(group fiber.Router) {
+	group.Use(a.handler.Authenticate)
+	group.Get("/accounts", a.HandleAccounts)
+	group.Put("/accounts/:id/cookies", a.HandleUpdateCookies)
+	group.Post("/accounts/:id/rotate", a.HandleRotate)
+	group.Get("/accounts/:id/conversations", a.HandleConversations)
+	group.Get("/accounts/:id/conversations/:cid", a.HandleConversation)
+	group.Delete("/accounts/:id/conversations/:cid", a.HandleDeleteConversation)
+}
@@ -17,7 +17,7 @@ func NewClaudeController(h *handlers.ClaudeHandler) *ClaudeController {
 
 // HandleModels returns a list of models
 // @Summary List Claude models (Internal)
-// @Description Returns a list of Claude models
+// @Description Returns the models of the routing table in Claude format
 // @Tags Claude Compatible
 // @Accept json
 // @Produce json
@@ -29,12 +29,13 @@ func (c *ClaudeController) HandleModels(ctx *fiber.Ctx) error {
 
 // HandleModelByID returns details of a specific model
 // @Summary Get Claude model by ID
-// @Description Returns a specific Claude model by ID
+// @Description Returns a specific model of the routing table by ID
 // @Tags Claude Compatible
 // @Accept json
 // @Produce json
 // @Param model_id path string true "Model ID"
 // @Success 200 {object} models.ModelData
+// @Failure 404 {object} map[string]interface{}
 // @Router /claude/v1/models/{model_id} [get]
 func (c *ClaudeController) HandleModelByID(ctx *fiber.Ctx) error {
 	return c.handler.HandleModelByID(ctx)
@@ -71,7 +72,7 @@ func (c *ClaudeController) HandleCountTokens(ctx *fiber.Ctx) error {
 // Register registers the Claude routes onto the provided group
 func (c *ClaudeController) Register(group fiber.Router) {
 	group.Get("/models", c.HandleModels)
-	group.Get("/models/:model_id", c.HandleModelByID)
+	group.Get("/models/*", c.HandleModelByID) // Gem model IDs contain a slash
 	group.Post("/messages", c.HandleMessages)
 	group.Post("/messages/count_tokens", c.HandleCountTokens)
-}
\ No newline at end of file
+}
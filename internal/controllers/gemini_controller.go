@@ -8,7 +8,7 @@ import (
 
 // GeminiController registers Gemini endpoints and contains Swagger annotations.
 // Note: these are the v1beta (official) endpoints
-type GeminiController struct{
+type GeminiController struct {
 	handler *handlers.GeminiHandler
 }
 
@@ -18,7 +18,7 @@ func NewGeminiController(h *handlers.GeminiHandler) *GeminiController {
 
 // HandleV1BetaModels returns the list of models in Gemini format
 // @Summary List Gemini Models (v1beta)
-// @Description Returns models supported by the Gemini provider
+// @Description Returns the models of the routing table
 // @Tags Gemini v1beta
 // @Produce json
 // @Success 200 {object} models.GeminiModelsResponse
@@ -27,6 +27,19 @@ func (g *GeminiController) HandleV1BetaModels(ctx *fiber.Ctx) error {
 	return g.handler.HandleV1BetaModels(ctx)
 }
 
+// HandleV1BetaModelByID returns a specific model in Gemini format
+// @Summary Get Gemini Model (v1beta)
+// @Description Returns a specific model of the routing table by ID
+// @Tags Gemini v1beta
+// @Produce json
+// @Param model path string true "Model name"
+// @Success 200 {object} models.GeminiModel
+// @Failure 404 {object} map[string]interface{}
+// @Router /gemini/v1beta/models/{model} [get]
+func (g *GeminiController) HandleV1BetaModelByID(ctx *fiber.Ctx) error {
+	return g.handler.HandleV1BetaModelByID(ctx)
+}
+
 // HandleV1BetaGenerateContent handles the official Gemini generateContent endpoint
 // @Summary Generate Content (v1beta)
 // @Description Compatible with official Google Gemini API
@@ -57,6 +70,11 @@ func (g *GeminiController) HandleV1BetaStreamGenerateContent(ctx *fiber.Ctx) err
 // Register registers the Gemini routes on the provided router (typically a group)
 func (g *GeminiController) Register(group fiber.Router) {
 	group.Get("/models", g.HandleV1BetaModels)
+	group.Get("/models/*", g.HandleV1BetaModelByID)
 	group.Post("/models/:model\\:generateContent", g.HandleV1BetaGenerateContent)
 	group.Post("/models/:model\\:streamGenerateContent", g.HandleV1BetaStreamGenerateContent)
-}
\ No newline at end of file
+
+	// Gem model IDs contain a slash
+	group.Post("/models/gem/:gem\\:generateContent", g.HandleV1BetaGenerateContent)
+	group.Post("/models/gem/:gem\\:streamGenerateContent", g.HandleV1BetaStreamGenerateContent)
+}
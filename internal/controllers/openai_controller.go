@@ -7,7 +7,7 @@ import (
 )
 
 // OpenAIController registers OpenAI-compatible endpoints and contains Swagger annotations.
-type OpenAIController struct{
+type OpenAIController struct {
 	handler *handlers.OpenAIHandler
 }
 
@@ -27,6 +27,20 @@ func (c *OpenAIController) HandleModels(ctx *fiber.Ctx) error {
 	return c.handler.HandleModels(ctx)
 }
 
+// HandleModelByID returns details of a specific model
+// @Summary Get OpenAI model by ID
+// @Description Returns a specific model of the routing table by ID
+// @Tags OpenAI Compatible
+// @Accept json
+// @Produce json
+// @Param model_id path string true "Model ID"
+// @Success 200 {object} models.ModelData
+// @Failure 404 {object} models.ErrorResponse
+// @Router /openai/v1/models/{model_id} [get]
+func (c *OpenAIController) HandleModelByID(ctx *fiber.Ctx) error {
+	return c.handler.HandleModelByID(ctx)
+}
+
 // HandleChatCompletions accepts requests in OpenAI format
 // @Summary OpenAI-compatible chat completions
 // @Description Accepts requests in OpenAI format
@@ -45,5 +59,6 @@ func (c *OpenAIController) HandleChatCompletions(ctx *fiber.Ctx) error {
 // Register registers the OpenAI routes onto the provided group
 func (c *OpenAIController) Register(group fiber.Router) {
 	group.Get("/models", c.HandleModels)
+	group.Get("/models/*", c.HandleModelByID) // Gem model IDs contain a slash
 	group.Post("/chat/completions", c.HandleChatCompletions)
-}
\ No newline at end of file
+}
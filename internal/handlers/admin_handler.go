@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
+	"ai-bridges/internal/providers/gemini"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// defaultConversationLimit is how many conversations or turns are returned without a limit parameter
+const defaultConversationLimit = 20
+
+// AdminHandler manages the credentials and conversations of the Gemini accounts at runtime
+type AdminHandler struct {
+	client *gemini.Pool
+	token  string
+	log    *zap.Logger
+}
+
+func NewAdminHandler(client *gemini.Pool, cfg *config.Config) *AdminHandler {
+	return &AdminHandler{
+		client: client,
+		token:  cfg.Admin.Token,
+		log:    zap.NewNop(),
+	}
+}
+
+// SetLogger sets the logger for this handler
+func (h *AdminHandler) SetLogger(log *zap.Logger) {
+	h.log = log
+}
+
+// Enabled reports whether an admin token is configured
+func (h *AdminHandler) Enabled() bool {
+	return h.token != ""
+}
+
+// Authenticate rejects requests that do not carry the admin token as a bearer token
+func (h *AdminHandler) Authenticate(c *fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorToResponse(fmt.Errorf("invalid admin token"), "authentication_error"))
+	}
+	return c.Next()
+}
+
+// HandleAccounts returns the credential status of every account
+func (h *AdminHandler) HandleAccounts(c *fiber.Ctx) error {
+	statuses := h.client.Status()
+	accounts := make([]models.AccountStatus, 0, len(statuses))
+	for _, s := range statuses {
+		accounts = append(accounts, models.AccountStatus{
+			ID:              s.ID,
+			Healthy:         s.Healthy,
+			Ejected:         s.Ejected,
+			InFlight:        s.InFlight,
+			UpdatedAt:       s.UpdatedAt,
+			LastRotation:    optionalTime(s.LastRotation),
+			NextRotation:    optionalTime(s.NextRotation),
+			LastAuthError:   s.LastAuthError,
+			LastAuthErrorAt: optionalTime(s.LastAuthErrorAt),
+		})
+	}
+	return c.JSON(models.AccountStatusResponse{Accounts: accounts})
+}
+
+// HandleUpdateCookies signs an account in with new cookies
+func (h *AdminHandler) HandleUpdateCookies(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid account id"), "invalid_request_error"))
+	}
+
+	var req models.UpdateCookiesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid request body: %w", err), "invalid_request_error"))
+	}
+	if req.Secure1PSID == "" && req.Cookies == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("__Secure-1PSID or cookies is required"), "invalid_request_error"))
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 2*time.Minute)
+	defer cancel()
+
+	err = h.client.UpdateCookies(ctx, id, gemini.Credentials{
+		Secure1PSID:   req.Secure1PSID,
+		Secure1PSIDTS: req.Secure1PSIDTS,
+		Secure1PSIDCC: req.Secure1PSIDCC,
+		Cookies:       req.Cookies,
+	})
+	if err != nil {
+		h.log.Warn("Cookie update failed", zap.Int("account", id), zap.Error(err))
+		return h.accountError(c, err)
+	}
+
+	h.log.Info("Cookies updated through the admin API", zap.Int("account", id))
+	return h.HandleAccounts(c)
+}
+
+// HandleRotate rotates an account's cookies right away
+func (h *AdminHandler) HandleRotate(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid account id"), "invalid_request_error"))
+	}
+
+	if err := h.client.RotateCookies(id); err != nil {
+		h.log.Warn("Forced cookie rotation failed", zap.Int("account", id), zap.Error(err))
+		return h.accountError(c, err)
+	}
+	return h.HandleAccounts(c)
+}
+
+// HandleConversations lists the recent conversations of an account
+func (h *AdminHandler) HandleConversations(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid account id"), "invalid_request_error"))
+	}
+	limit := c.QueryInt("limit", defaultConversationLimit)
+	if limit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("limit must be positive"), "invalid_request_error"))
+	}
+
+	list, err := h.client.ListConversations(c.Context(), id, limit)
+	if err != nil {
+		h.log.Warn("Listing conversations failed", zap.Int("account", id), zap.Error(err))
+		return h.accountError(c, err)
+	}
+
+	conversations := make([]models.Conversation, 0, len(list))
+	for _, conv := range list {
+		conversations = append(conversations, models.Conversation{
+			ID:        conv.ID,
+			Title:     conv.Title,
+			UpdatedAt: optionalTime(conv.UpdatedAt),
+		})
+	}
+	return c.JSON(models.ConversationListResponse{Conversations: conversations})
+}
+
+// HandleConversation returns the turns of one of an account's conversations
+func (h *AdminHandler) HandleConversation(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid account id"), "invalid_request_error"))
+	}
+	limit := c.QueryInt("limit", defaultConversationLimit)
+	if limit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("limit must be positive"), "invalid_request_error"))
+	}
+
+	conversationID := c.Params("cid")
+	list, err := h.client.ReadConversation(c.Context(), id, conversationID, limit)
+	if err != nil {
+		h.log.Warn("Reading conversation failed", zap.Int("account", id), zap.String("conversation", conversationID), zap.Error(err))
+		return h.accountError(c, err)
+	}
+
+	turns := make([]models.ConversationTurn, 0, len(list))
+	for _, turn := range list {
+		turns = append(turns, models.ConversationTurn{
+			ResponseID:  turn.ResponseID,
+			CandidateID: turn.CandidateID,
+			Prompt:      turn.Prompt,
+			Response:    turn.Response,
+			CreatedAt:   optionalTime(turn.CreatedAt),
+		})
+	}
+	return c.JSON(models.ConversationResponse{ID: conversationID, Turns: turns})
+}
+
+// HandleDeleteConversation deletes one of an account's conversations
+func (h *AdminHandler) HandleDeleteConversation(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid account id"), "invalid_request_error"))
+	}
+
+	conversationID := c.Params("cid")
+	if err := h.client.DeleteConversation(c.Context(), id, conversationID); err != nil {
+		h.log.Warn("Deleting conversation failed", zap.Int("account", id), zap.String("conversation", conversationID), zap.Error(err))
+		return h.accountError(c, err)
+	}
+
+	h.log.Info("Conversation deleted through the admin API", zap.Int("account", id), zap.String("conversation", conversationID))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// accountError reports an unknown account as 404 and anything else as a provider error
+func (h *AdminHandler) accountError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gemini.ErrUnknownAccount) {
+		return c.Status(fiber.StatusNotFound).JSON(errorToResponse(err, "not_found_error"))
+	}
+	status, body := openAIError(err)
+	if status == fiber.StatusInternalServerError || errors.Is(err, providers.ErrAuthExpired) {
+		// Cookies that cannot be parsed, rotated or signed in with are the caller's to fix
+		status = fiber.StatusUnprocessableEntity
+	}
+	return c.Status(status).JSON(body)
+}
+
+// optionalTime omits zero times from responses
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -4,11 +4,11 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"ai-bridges/internal/models"
 	"ai-bridges/internal/providers"
-	"ai-bridges/internal/providers/gemini"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -16,14 +16,14 @@ import (
 )
 
 type ClaudeHandler struct {
-	client *gemini.Client
-	log    *zap.Logger
+	providers *providers.ProviderManager
+	log       *zap.Logger
 }
 
-func NewClaudeHandler(client *gemini.Client) *ClaudeHandler {
+func NewClaudeHandler(pm *providers.ProviderManager) *ClaudeHandler {
 	return &ClaudeHandler{
-		client: client,
-		log:    zap.NewNop(),
+		providers: pm,
+		log:       zap.NewNop(),
 	}
 }
 
@@ -34,45 +34,41 @@ func (h *ClaudeHandler) SetLogger(log *zap.Logger) {
 
 // GetModelData moved to models_handlers.go
 
-// HandleModels returns a list of Claude models
+// HandleModels returns the models of the routing table in Claude format
 func (h *ClaudeHandler) HandleModels(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"data": []fiber.Map{
-			{
-				"id":           "claude-3-5-sonnet-20240620",
-				"type":         "model",
-				"created_at":   1718841600,
-				"display_name": "Claude 3.5 Sonnet",
-			},
-			{
-				"id":           "claude-3-opus-20240229",
-				"type":         "model",
-				"created_at":   1709164800,
-				"display_name": "Claude 3 Opus",
-			},
-			{
-				"id":           "claude-3-7-sonnet-20250219",
-				"type":         "model",
-				"created_at":   1739923200,
-				"display_name": "Claude 3.7 Sonnet",
-			},
-		},
-	})
+	data := []models.ModelData{}
+	for _, m := range h.providers.ListModels() {
+		data = append(data, claudeModel(m))
+	}
+	return c.JSON(fiber.Map{"data": data})
 }
 
-// HandleModelByID returns a specific Claude model by ID
+// HandleModelByID returns a model of the routing table in Claude format
 func (h *ClaudeHandler) HandleModelByID(c *fiber.Ctx) error {
-	modelID := c.Params("model_id")
-	return c.JSON(fiber.Map{
-		"id":           modelID,
-		"type":         "model",
-		"created_at":   time.Now().Unix(),
-		"display_name": modelID,
-	})
+	m, err := h.providers.Model(c.Params("*"))
+	if err != nil {
+		status, body := claudeError(err)
+		return c.Status(status).JSON(body)
+	}
+	return c.JSON(claudeModel(m))
 }
 
-// Model handlers moved to models_handlers.go
+// claudeModel converts a model into a Claude model object
+func claudeModel(m providers.ModelInfo) models.ModelData {
+	displayName := m.Name
+	if displayName == "" {
+		displayName = m.ID
+	}
+	return models.ModelData{
+		ID:           m.ID,
+		Type:         "model",
+		CreatedAt:    m.Created,
+		DisplayName:  displayName,
+		Capabilities: m.Capabilities,
+	}
+}
 
+// Model handlers moved to models_handlers.go
 
 // HandleMessages handles the main chat endpoint
 func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
@@ -101,34 +97,78 @@ func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
 		})
 	}
 
+	files, err := collectFiles(req.Messages)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"type":  "error",
+			"error": fiber.Map{"type": "invalid_request_error", "message": err.Error()},
+		})
+	}
+
+	if req.CandidateCount < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"type":  "error",
+			"error": fiber.Map{"type": "invalid_request_error", "message": "candidate_count must be non-negative"},
+		})
+	}
+	// Streamed messages have a single content array, so there is nowhere to put more candidates
+	if req.CandidateCount > 1 && req.Stream {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"type":  "error",
+			"error": fiber.Map{"type": "invalid_request_error", "message": "candidate_count above 1 is not supported with stream"},
+		})
+	}
+
+	locale, err := requestLocale(c, req.Locale)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"type":  "error",
+			"error": fiber.Map{"type": "invalid_request_error", "message": err.Error()},
+		})
+	}
+
+	route, err := h.providers.Resolve(req.Model)
+	if err != nil {
+		status, body := claudeError(err)
+		return c.Status(status).JSON(body)
+	}
+
 	opts := []providers.GenerateOption{}
+	if locale != "" {
+		opts = append(opts, providers.WithLocale(locale))
+	}
+	if len(files) > 0 {
+		opts = append(opts, providers.WithFiles(files))
+	}
+	if req.CandidateCount > 1 {
+		opts = append(opts, providers.WithCandidateCount(req.CandidateCount))
+	}
+	thinking := req.Thinking != nil && req.Thinking.Type == "enabled"
+	if thinking {
+		opts = append(opts, providers.WithThoughts(true))
+	}
 	msgID := fmt.Sprintf("msg_%s", uuid.New().String())
 
 	// Handle Streaming
 	if req.Stream {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
+
+		stream, attempt, err := h.providers.GenerateStream(ctx, route, prompt, opts...)
+		if err != nil {
+			cancel()
+			h.log.Error("GenerateContentStream failed", zap.Error(err), zap.String("model", req.Model))
+			status, body := claudeError(err)
+			return c.Status(status).JSON(body)
+		}
+		setFallbackHeader(c, attempt)
+
 		c.Set("Content-Type", "text/event-stream")
 		c.Set("Cache-Control", "no-cache")
 		c.Set("Connection", "keep-alive")
 
 		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-			// Add timeout
-			ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 			defer cancel()
 
-			response, err := h.client.GenerateContent(ctx, prompt, opts...)
-			if err != nil {
-				h.log.Error("GenerateContent streaming failed", zap.Error(err), zap.String("model", req.Model))
-				_ = sendSSEChunk(w, h.log, "error", fiber.Map{
-					"type": "error",
-					"error": fiber.Map{
-						"type":    "api_error",
-						"message": err.Error(),
-					},
-				})
-				return
-			}
-
-			// Simulate Streaming - Claude format
 			_ = sendSSEChunk(w, h.log, "message_start", fiber.Map{
 				"type": "message_start",
 				"message": models.MessageResponse{
@@ -140,29 +180,108 @@ func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
 				},
 			})
 
-			_ = sendSSEChunk(w, h.log, "content_block_start", fiber.Map{
-				"type":           "content_block_start",
-				"index":          0,
-				"content_block":  models.ConfigContent{Type: "text", Text: ""},
-			})
-
-			// Send chunks
-			chunks := splitResponseIntoChunks(response.Text, 20)
-			for _, chunk := range chunks {
-				_ = sendSSEChunk(w, h.log, "content_block_delta", fiber.Map{
-					"type":  "content_block_delta",
-					"index": 0,
-					"delta": models.Delta{Type: "text_delta", Text: chunk},
+			// Blocks are opened lazily: a thinking block while thoughts arrive, then a text block
+			index := -1
+			openType := ""
+			startBlock := func(block models.ConfigContent) error {
+				if openType != "" {
+					_ = sendSSEChunk(w, h.log, "content_block_stop", fiber.Map{"type": "content_block_stop", "index": index})
+				}
+				index++
+				openType = block.Type
+				return sendSSEChunk(w, h.log, "content_block_start", fiber.Map{
+					"type":          "content_block_start",
+					"index":         index,
+					"content_block": block,
 				})
+			}
+
+			var final *providers.Response
+			var streamed strings.Builder
+			for chunk := range stream {
+				if chunk.Err != nil {
+					h.log.Error("Stream failed", zap.Error(chunk.Err), zap.String("model", req.Model))
+					_, body := claudeError(chunk.Err)
+					_ = sendSSEChunk(w, h.log, "error", body)
+					return
+				}
+				if chunk.Response != nil {
+					final = chunk.Response
+				}
 
-				// Check context cancellation
-				if !sleepWithCancel(c.Context(), 20*time.Millisecond) {
+				var delta models.Delta
+				switch {
+				case chunk.Thought != "":
+					if openType != "thinking" {
+						if err := startBlock(models.ConfigContent{Type: "thinking"}); err != nil {
+							h.log.Info("Stream cancelled by client")
+							return
+						}
+					}
+					delta = models.Delta{Type: "thinking_delta", Thinking: chunk.Thought}
+				case chunk.Text != "":
+					if openType != "text" {
+						if err := startBlock(models.ConfigContent{Type: "text", Text: ""}); err != nil {
+							h.log.Info("Stream cancelled by client")
+							return
+						}
+					}
+					delta = models.Delta{Type: "text_delta", Text: chunk.Text}
+					streamed.WriteString(chunk.Text)
+				default:
+					continue
+				}
+
+				if err := sendSSEChunk(w, h.log, "content_block_delta", fiber.Map{
+					"type":  "content_block_delta",
+					"index": index,
+					"delta": delta,
+				}); err != nil {
 					h.log.Info("Stream cancelled by client")
 					return
 				}
 			}
 
-			_ = sendSSEChunk(w, h.log, "content_block_stop", fiber.Map{"type": "content_block_stop", "index": 0})
+			// Always emit a text block, even for an empty answer
+			if openType != "text" {
+				_ = startBlock(models.ConfigContent{Type: "text", Text: ""})
+			}
+
+			// Citations are only known once the final response arrives
+			if final != nil {
+				for _, citation := range textCitations(final.Text, final.Citations) {
+					_ = sendSSEChunk(w, h.log, "content_block_delta", fiber.Map{
+						"type":  "content_block_delta",
+						"index": index,
+						"delta": models.Delta{Type: "citations_delta", Citation: &citation},
+					})
+				}
+			}
+			_ = sendSSEChunk(w, h.log, "content_block_stop", fiber.Map{"type": "content_block_stop", "index": index})
+
+			// Images are only known once the final response arrives
+			if final != nil {
+				for _, block := range imageBlocks(final.Images) {
+					index++
+					_ = sendSSEChunk(w, h.log, "content_block_start", fiber.Map{
+						"type":          "content_block_start",
+						"index":         index,
+						"content_block": block,
+					})
+					_ = sendSSEChunk(w, h.log, "content_block_stop", fiber.Map{"type": "content_block_stop", "index": index})
+				}
+			}
+
+			// The stop reason and usage are reported once the answer is complete, counted like non-streaming responses
+			text := streamed.String()
+			if final != nil {
+				text = final.Text
+			}
+			_ = sendSSEChunk(w, h.log, "message_delta", fiber.Map{
+				"type":  "message_delta",
+				"delta": fiber.Map{"stop_reason": "end_turn", "stop_sequence": nil},
+				"usage": fiber.Map{"output_tokens": len(text) / 4},
+			})
 			_ = sendSSEChunk(w, h.log, "message_stop", fiber.Map{"type": "message_stop", "stop_reason": "end_turn"})
 		})
 		return nil
@@ -172,17 +291,26 @@ func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 	defer cancel()
 
-	response, err := h.client.GenerateContent(ctx, prompt, opts...)
+	response, attempt, err := h.providers.Generate(ctx, route, prompt, opts...)
 	if err != nil {
 		h.log.Error("GenerateContent failed", zap.Error(err), zap.String("model", req.Model))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"type":  "error",
-			"error": fiber.Map{"type": "api_error", "message": err.Error()},
-		})
+		status, body := claudeError(err)
+		return c.Status(status).JSON(body)
 	}
+	setFallbackHeader(c, attempt)
 
 	// Construct Response
-	content := []models.ConfigContent{{Type: "text", Text: response.Text}}
+	content := messageContent(response.Text, response.Thoughts, response.Images, response.Citations)
+
+	var candidates []models.MessageCandidate
+	if req.CandidateCount > 1 {
+		for i, candidate := range selectCandidates(response, req.CandidateCount) {
+			candidates = append(candidates, models.MessageCandidate{
+				Index:   i,
+				Content: messageContent(candidate.Content, candidate.Thoughts, candidate.Images, candidate.Citations),
+			})
+		}
+	}
 
 	return c.JSON(models.MessageResponse{
 		ID:         msgID,
@@ -190,6 +318,7 @@ func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
 		Role:       "assistant",
 		Model:      req.Model,
 		Content:    content,
+		Candidates: candidates,
 		StopReason: "end_turn",
 		Usage: models.Usage{
 			InputTokens:  len(prompt) / 4,
@@ -198,6 +327,43 @@ func (h *ClaudeHandler) HandleMessages(c *fiber.Ctx) error {
 	})
 }
 
+// messageContent builds the content blocks of a Claude message: thinking first, then text and images
+func messageContent(text, thoughts string, images []providers.Image, citations []providers.Citation) []models.ConfigContent {
+	var content []models.ConfigContent
+	if thoughts != "" {
+		content = append(content, models.ConfigContent{Type: "thinking", Thinking: thoughts})
+	}
+	content = append(content, models.ConfigContent{Type: "text", Text: text, Citations: textCitations(text, citations)})
+	return append(content, imageBlocks(images)...)
+}
+
+// textCitations converts citations of text into Claude web search citations
+func textCitations(text string, citations []providers.Citation) []models.TextCitation {
+	var result []models.TextCitation
+	for _, citation := range citations {
+		start, end := citationSpan(text, citation)
+		result = append(result, models.TextCitation{
+			Type:      "web_search_result_location",
+			URL:       citation.URL,
+			Title:     citation.Title,
+			CitedText: text[start:end],
+		})
+	}
+	return result
+}
+
+// imageBlocks converts response images into Claude image content blocks
+func imageBlocks(images []providers.Image) []models.ConfigContent {
+	var blocks []models.ConfigContent
+	for _, img := range images {
+		blocks = append(blocks, models.ConfigContent{
+			Type:   "image",
+			Source: &models.ImageSource{Type: "url", URL: img.URL},
+		})
+	}
+	return blocks
+}
+
 // HandleCountTokens handles token counting
 func (h *ClaudeHandler) HandleCountTokens(c *fiber.Ctx) error {
 	var req models.MessageRequest
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newClaudeTestApp(t *testing.T, fake *fakeProvider) *fiber.App {
+	t.Helper()
+	h := NewClaudeHandler(newTestManager(t, fake))
+	app := fiber.New()
+	app.Post("/v1/messages", h.HandleMessages)
+	return app
+}
+
+func TestClaudeMessages(t *testing.T) {
+	app := newClaudeTestApp(t, &fakeProvider{})
+
+	resp, body := post(t, app, "/v1/messages", `{"model": "test-model", "max_tokens": 100, "system": "Be brief", "messages": [{"role": "user", "content": "Say hello"}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+
+	var message models.MessageResponse
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if message.Type != "message" || message.Role != "assistant" || message.Model != "test-model" || message.StopReason != "end_turn" {
+		t.Errorf("unexpected message %s", body)
+	}
+	if len(message.Content) != 1 || message.Content[0].Type != "text" || message.Content[0].Text != "Hello, world" {
+		t.Fatalf("unexpected content %+v", message.Content)
+	}
+	if citations := message.Content[0].Citations; len(citations) != 1 || citations[0].URL != "https://example.com" || citations[0].CitedText != "Hello" {
+		t.Errorf("unexpected citations %+v", citations)
+	}
+}
+
+func TestClaudeMessagesStream(t *testing.T) {
+	app := newClaudeTestApp(t, &fakeProvider{})
+
+	resp, body := post(t, app, "/v1/messages", `{"model": "test-model", "max_tokens": 100, "stream": true, "thinking": {"type": "enabled"}, "messages": [{"role": "user", "content": "Say hello"}]}`)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var names []string
+	var thinking, text strings.Builder
+	for _, event := range parseSSE(t, body) {
+		var data struct {
+			Type  string       `json:"type"`
+			Index int          `json:"index"`
+			Delta models.Delta `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(event.data), &data); err != nil {
+			t.Fatalf("decode %s event: %v", event.name, err)
+		}
+		if data.Type != event.name {
+			t.Errorf("%s event has data of type %q", event.name, data.Type)
+		}
+		names = append(names, event.name)
+		if event.name == "content_block_delta" {
+			thinking.WriteString(data.Delta.Thinking)
+			text.WriteString(data.Delta.Text)
+		}
+		if event.name == "message_delta" && event.data != `{"delta":{"stop_reason":"end_turn","stop_sequence":null},"type":"message_delta","usage":{"output_tokens":3}}` {
+			t.Errorf("unexpected message_delta %s", event.data)
+		}
+	}
+
+	want := []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop", // thinking
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_delta", "content_block_stop", // text and its citation
+		"message_delta",
+		"message_stop",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("events %v, want %v", names, want)
+	}
+	if thinking.String() != "Thinking." || text.String() != "Hello, world" {
+		t.Errorf("streamed thinking %q and text %q", thinking.String(), text.String())
+	}
+}
+
+func TestClaudeErrors(t *testing.T) {
+	app := newClaudeTestApp(t, &fakeProvider{err: providers.ErrContentBlocked})
+
+	for _, stream := range []string{"false", "true"} {
+		resp, body := post(t, app, "/v1/messages", `{"model": "test-model", "max_tokens": 100, "stream": `+stream+`, "messages": [{"role": "user", "content": "hi"}]}`)
+		if resp.StatusCode != fiber.StatusBadRequest || !strings.Contains(body, `"type":"invalid_request_error"`) {
+			t.Errorf("content block, stream %s: status %d, body %s", stream, resp.StatusCode, body)
+		}
+	}
+
+	resp, body := post(t, app, "/v1/messages", `{"model": "gpt-4", "max_tokens": 100, "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusNotFound || !strings.Contains(body, `"type":"not_found_error"`) {
+		t.Errorf("unknown model: status %d, body %s", resp.StatusCode, body)
+	}
+
+	// Streamed messages carry a single candidate
+	resp, body = post(t, app, "/v1/messages", `{"model": "test-model", "max_tokens": 100, "stream": true, "candidate_count": 2, "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusBadRequest || !strings.Contains(body, "candidate_count") {
+		t.Errorf("streamed candidates: status %d, body %s", resp.StatusCode, body)
+	}
+}
+
+func TestClaudeStreamErrorAfterFirstChunk(t *testing.T) {
+	app := newClaudeTestApp(t, &fakeProvider{streamErr: providers.ErrRateLimited})
+
+	resp, body := post(t, app, "/v1/messages", `{"model": "test-model", "max_tokens": 100, "stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	events := parseSSE(t, body)
+	last := events[len(events)-1]
+	if last.name != "error" || !strings.Contains(last.data, `"type":"rate_limit_error"`) {
+		t.Errorf("stream ends with %s event %s, want a rate_limit_error", last.name, last.data)
+	}
+}
@@ -3,29 +3,30 @@ package handlers
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"ai-bridges/internal/models"
 	"ai-bridges/internal/providers"
-	"ai-bridges/internal/providers/gemini"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 type GeminiHandler struct {
-	client *gemini.Client
-	log    *zap.Logger
-	mu     sync.RWMutex
+	providers *providers.ProviderManager
+	log       *zap.Logger
+	mu        sync.RWMutex
 }
 
-func NewGeminiHandler(client *gemini.Client) *GeminiHandler {
+func NewGeminiHandler(pm *providers.ProviderManager) *GeminiHandler {
 	return &GeminiHandler{
-		client: client,
-		log:    zap.NewNop(), // Will be injected via wire if needed
+		providers: pm,
+		log:       zap.NewNop(), // Will be injected via wire if needed
 	}
 }
 
@@ -36,14 +37,14 @@ func (h *GeminiHandler) SetLogger(log *zap.Logger) {
 	h.log = log
 }
 
-// IsHealthy returns the health status of the underlying Gemini client
+// IsHealthy reports whether any provider can serve requests
 func (h *GeminiHandler) IsHealthy() bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	if h.client == nil {
+	if h.providers == nil {
 		return false
 	}
-	return h.client.IsHealthy()
+	return h.providers.IsHealthy()
 }
 
 // --- Official Gemini API (v1beta) ---
@@ -53,68 +54,85 @@ func (h *GeminiHandler) HandleV1BetaModels(c *fiber.Ctx) error {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	availableModels := h.client.ListModels()
+	availableModels := h.providers.ListModels()
 	var geminiModels []models.GeminiModel
 	for _, m := range availableModels {
-		geminiModels = append(geminiModels, models.GeminiModel{
-			Name:                       "models/" + m.ID,
-			DisplayName:                m.ID,
-			SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent"},
-		})
+		geminiModels = append(geminiModels, geminiModel(m))
 	}
 	return c.JSON(models.GeminiModelsResponse{Models: geminiModels})
 }
 
-// HandleV1BetaGenerateContent handles the official Gemini generateContent endpoint
-func (h *GeminiHandler) HandleV1BetaGenerateContent(c *fiber.Ctx) error {
+// HandleV1BetaModelByID returns a model of the routing table in Gemini format
+func (h *GeminiHandler) HandleV1BetaModelByID(c *fiber.Ctx) error {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	model := c.Params("model")
-	var req models.GeminiGenerateRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid request body: %w", err), "invalid_request_error"))
+	m, err := h.providers.Model(c.Params("*"))
+	if err != nil {
+		status, body := geminiError(err)
+		return c.Status(status).JSON(body)
 	}
+	return c.JSON(geminiModel(m))
+}
 
-	// Extract prompt from contents
-	var promptBuilder strings.Builder
-	for _, content := range req.Contents {
-		for _, part := range content.Parts {
-			if part.Text != "" {
-				promptBuilder.WriteString(part.Text)
-				promptBuilder.WriteString("\n")
-			}
-		}
+// geminiModel converts a model into a Gemini model resource
+func geminiModel(m providers.ModelInfo) models.GeminiModel {
+	displayName := m.ID
+	if m.Name != "" {
+		displayName = m.Name
 	}
-
-	prompt := strings.TrimSpace(promptBuilder.String())
-	if prompt == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("empty content"), "invalid_request_error"))
+	return models.GeminiModel{
+		Name:                       "models/" + m.ID,
+		DisplayName:                displayName,
+		Description:                m.Description,
+		SupportedGenerationMethods: []string{"generateContent", "streamGenerateContent"},
+		Thinking:                   slices.Contains(m.Capabilities, providers.CapabilityThinking),
 	}
+}
+
+// HandleV1BetaGenerateContent handles the official Gemini generateContent endpoint
+func (h *GeminiHandler) HandleV1BetaGenerateContent(c *fiber.Ctx) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	opts := []providers.GenerateOption{providers.WithModel(model)}
+	model := modelParam(c)
+	var req models.GeminiGenerateRequest
+	route, prompt, opts, err := h.parseGenerateRequest(c, &req)
+	if err != nil {
+		status, body := generateRequestError(err)
+		return c.Status(status).JSON(body)
+	}
+	candidateCount := geminiCandidateCount(&req)
 
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 	defer cancel()
 
-	response, err := h.client.GenerateContent(ctx, prompt, opts...)
+	response, attempt, err := h.providers.Generate(ctx, route, prompt, opts...)
 	if err != nil {
 		h.log.Error("GenerateContent failed", zap.Error(err), zap.String("model", model))
-		return c.Status(fiber.StatusInternalServerError).JSON(errorToResponse(err, "api_error"))
+		status, body := geminiError(err)
+		return c.Status(status).JSON(body)
+	}
+	setFallbackHeader(c, attempt)
+
+	var candidates []models.Candidate
+	for i, candidate := range selectCandidates(response, candidateCount) {
+		grounding, citations := groundingMetadata(candidate.Content, candidate.Citations)
+		candidates = append(candidates, models.Candidate{
+			Index: i,
+			Content: models.Content{
+				Role:  "model",
+				Parts: candidateParts(candidate),
+			},
+			FinishReason:      "STOP",
+			GroundingMetadata: grounding,
+			CitationMetadata:  citations,
+		})
 	}
 
 	return c.JSON(models.GeminiGenerateResponse{
-		Candidates: []models.Candidate{
-			{
-				Index: 0,
-				Content: models.Content{
-					Role:  "model",
-					Parts: []models.Part{{Text: response.Text}},
-				},
-				FinishReason: "STOP",
-			},
-		},
+		Candidates: candidates,
 		UsageMetadata: &models.UsageMetadata{
 			TotalTokenCount: 0,
 		},
@@ -126,54 +144,56 @@ func (h *GeminiHandler) HandleV1BetaStreamGenerateContent(c *fiber.Ctx) error {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	model := c.Params("model")
+	model := modelParam(c)
 	var req models.GeminiGenerateRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("invalid request body: %w", err), "invalid_request_error"))
+	route, prompt, opts, err := h.parseGenerateRequest(c, &req)
+	if err != nil {
+		status, body := generateRequestError(err)
+		return c.Status(status).JSON(body)
 	}
+	candidateCount := geminiCandidateCount(&req)
 
-	var promptBuilder strings.Builder
-	for _, content := range req.Contents {
-		for _, part := range content.Parts {
-			if part.Text != "" {
-				promptBuilder.WriteString(part.Text)
-				promptBuilder.WriteString("\n")
-			}
-		}
-	}
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 
-	prompt := strings.TrimSpace(promptBuilder.String())
-	if prompt == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("empty content"), "invalid_request_error"))
+	stream, attempt, err := h.providers.GenerateStream(ctx, route, prompt, opts...)
+	if err != nil {
+		cancel()
+		h.log.Error("GenerateContentStream failed", zap.Error(err), zap.String("model", model))
+		status, body := geminiError(err)
+		return c.Status(status).JSON(body)
 	}
-
-	opts := []providers.GenerateOption{providers.WithModel(model)}
+	setFallbackHeader(c, attempt)
 
 	c.Set("Content-Type", "application/json")
 	c.Set("Transfer-Encoding", "chunked")
 
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		// Add timeout to context
-		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 		defer cancel()
 
-		resp, err := h.client.GenerateContent(ctx, prompt, opts...)
-		if err != nil {
-			h.log.Error("GenerateContent streaming failed", zap.Error(err), zap.String("model", model))
-			errResponse := errorToResponse(err, "api_error")
-			_ = sendStreamChunk(w, h.log, errResponse)
-			return
-		}
+		i := 0
+		var final *providers.Response
+		for streamChunk := range stream {
+			if streamChunk.Err != nil {
+				h.log.Error("Stream failed", zap.Error(streamChunk.Err), zap.String("model", model))
+				_, body := geminiError(streamChunk.Err)
+				_ = sendStreamChunk(w, h.log, body)
+				return
+			}
+			if streamChunk.Response != nil {
+				final = streamChunk.Response
+			}
+			if streamChunk.Text == "" && streamChunk.Thought == "" {
+				continue
+			}
 
-		chunks := splitResponseIntoChunks(resp.Text, 30)
-		for i, content := range chunks {
 			chunk := models.GeminiGenerateResponse{
 				Candidates: []models.Candidate{
 					{
 						Index: 0,
 						Content: models.Content{
 							Role:  "model",
-							Parts: []models.Part{{Text: content}},
+							Parts: deltaParts(streamChunk),
 						},
 					},
 				},
@@ -183,15 +203,10 @@ func (h *GeminiHandler) HandleV1BetaStreamGenerateContent(c *fiber.Ctx) error {
 				h.log.Error("Failed to send stream chunk", zap.Error(err), zap.Int("chunk_index", i))
 				return
 			}
-
-			// Check for context cancellation and sleep
-			if !sleepWithCancel(c.Context(), 30*time.Millisecond) {
-				h.log.Info("Stream cancelled by client")
-				return
-			}
+			i++
 		}
 
-		// Send final chunk
+		// Send final chunk, carrying citations, images and alternative candidates from the final response
 		finalChunk := models.GeminiGenerateResponse{
 			Candidates: []models.Candidate{
 				{
@@ -200,10 +215,199 @@ func (h *GeminiHandler) HandleV1BetaStreamGenerateContent(c *fiber.Ctx) error {
 				},
 			},
 		}
+		if final != nil {
+			finalChunk.Candidates[0].GroundingMetadata, finalChunk.Candidates[0].CitationMetadata = groundingMetadata(final.Text, final.Citations)
+			if len(final.Images) > 0 {
+				finalChunk.Candidates[0].Content = models.Content{
+					Role:  "model",
+					Parts: imageParts(final.Images),
+				}
+			}
+
+			// Alternative candidates are sent whole with the final chunk
+			candidates := selectCandidates(final, candidateCount)
+			for i := 1; i < len(candidates); i++ {
+				grounding, citations := groundingMetadata(candidates[i].Content, candidates[i].Citations)
+				finalChunk.Candidates = append(finalChunk.Candidates, models.Candidate{
+					Index: i,
+					Content: models.Content{
+						Role:  "model",
+						Parts: candidateParts(candidates[i]),
+					},
+					FinishReason:      "STOP",
+					GroundingMetadata: grounding,
+					CitationMetadata:  citations,
+				})
+			}
+		}
 		_ = sendStreamChunk(w, h.log, finalChunk)
 	})
 
 	return nil
 }
 
+// invalidArgumentError is a malformed generate request, answered with 400 INVALID_ARGUMENT
+type invalidArgumentError struct {
+	err error
+}
 
+func (e *invalidArgumentError) Error() string { return e.err.Error() }
+func (e *invalidArgumentError) Unwrap() error { return e.err }
+
+// parseGenerateRequest reads a generateContent or streamGenerateContent request into req and
+// returns the route of the model in the path, the prompt and the generation options.
+// Malformed requests fail with an invalidArgumentError; see generateRequestError.
+func (h *GeminiHandler) parseGenerateRequest(c *fiber.Ctx, req *models.GeminiGenerateRequest) (providers.Route, string, []providers.GenerateOption, error) {
+	if err := c.BodyParser(req); err != nil {
+		return providers.Route{}, "", nil, &invalidArgumentError{fmt.Errorf("invalid request body: %w", err)}
+	}
+
+	// Extract prompt from contents
+	var promptBuilder strings.Builder
+	for _, content := range req.Contents {
+		for _, part := range content.Parts {
+			if part.Text != "" {
+				promptBuilder.WriteString(part.Text)
+				promptBuilder.WriteString("\n")
+			}
+		}
+	}
+
+	files, err := collectGeminiFiles(req.Contents)
+	if err != nil {
+		return providers.Route{}, "", nil, &invalidArgumentError{err}
+	}
+
+	prompt := strings.TrimSpace(promptBuilder.String())
+	if prompt == "" && len(files) == 0 {
+		return providers.Route{}, "", nil, &invalidArgumentError{fmt.Errorf("empty content")}
+	}
+
+	locale, err := requestLocale(c, req.Locale)
+	if err != nil {
+		return providers.Route{}, "", nil, &invalidArgumentError{err}
+	}
+
+	route, err := h.providers.Resolve(modelParam(c))
+	if err != nil {
+		return providers.Route{}, "", nil, err
+	}
+
+	opts := []providers.GenerateOption{}
+	if locale != "" {
+		opts = append(opts, providers.WithLocale(locale))
+	}
+	if len(files) > 0 {
+		opts = append(opts, providers.WithFiles(files))
+	}
+	if candidateCount := geminiCandidateCount(req); candidateCount > 1 {
+		opts = append(opts, providers.WithCandidateCount(candidateCount))
+	}
+	if req.GenerationConfig != nil && req.GenerationConfig.ThinkingConfig != nil && req.GenerationConfig.ThinkingConfig.IncludeThoughts {
+		opts = append(opts, providers.WithThoughts(true))
+	}
+	return route, prompt, opts, nil
+}
+
+// generateRequestError converts an error of parseGenerateRequest to a status code and error body
+func generateRequestError(err error) (int, models.GeminiErrorResponse) {
+	var invalid *invalidArgumentError
+	if errors.As(err, &invalid) {
+		return fiber.StatusBadRequest, geminiErrorBody(fiber.StatusBadRequest, "INVALID_ARGUMENT", invalid.err)
+	}
+	return geminiError(err)
+}
+
+// geminiCandidateCount returns the number of candidates a request asks for, 0 when unset
+func geminiCandidateCount(req *models.GeminiGenerateRequest) int {
+	if req.GenerationConfig == nil {
+		return 0
+	}
+	return int(req.GenerationConfig.CandidateCount)
+}
+
+// modelParam returns the model named in the request path, including Gem models
+func modelParam(c *fiber.Ctx) string {
+	if gem := c.Params("gem"); gem != "" {
+		return providers.GemModelPrefix + gem
+	}
+	return c.Params("model")
+}
+
+// groundingMetadata converts citations of text into Gemini grounding and citation metadata.
+// Each source becomes one grounding chunk; citations of the same span share a support.
+func groundingMetadata(text string, citations []providers.Citation) (*models.GroundingMetadata, *models.CitationMetadata) {
+	if len(citations) == 0 {
+		return nil, nil
+	}
+
+	grounding := &models.GroundingMetadata{}
+	sources := &models.CitationMetadata{}
+	chunks := make(map[string]int)
+	supports := make(map[[2]int]int)
+	for _, citation := range citations {
+		chunk, ok := chunks[citation.URL]
+		if !ok {
+			chunk = len(grounding.GroundingChunks)
+			chunks[citation.URL] = chunk
+			grounding.GroundingChunks = append(grounding.GroundingChunks, models.GroundingChunk{
+				Web: &models.WebSource{URI: citation.URL, Title: citation.Title},
+			})
+		}
+
+		start, end := citationSpan(text, citation)
+		span := [2]int{start, end}
+		if i, ok := supports[span]; ok {
+			grounding.GroundingSupports[i].GroundingChunkIndices = append(grounding.GroundingSupports[i].GroundingChunkIndices, chunk)
+		} else {
+			supports[span] = len(grounding.GroundingSupports)
+			grounding.GroundingSupports = append(grounding.GroundingSupports, models.GroundingSupport{
+				Segment:               models.Segment{StartIndex: start, EndIndex: end, Text: text[start:end]},
+				GroundingChunkIndices: []int{chunk},
+			})
+		}
+
+		sources.CitationSources = append(sources.CitationSources, models.CitationSource{
+			StartIndex: start,
+			EndIndex:   end,
+			URI:        citation.URL,
+		})
+	}
+	return grounding, sources
+}
+
+// candidateParts converts a candidate into Gemini parts: reasoning first, then text and images
+func candidateParts(candidate providers.Candidate) []models.Part {
+	var parts []models.Part
+	if candidate.Thoughts != "" {
+		parts = append(parts, models.Part{Text: candidate.Thoughts, Thought: true})
+	}
+	parts = append(parts, models.Part{Text: candidate.Content})
+	return append(parts, imageParts(candidate.Images)...)
+}
+
+// deltaParts converts a stream chunk into Gemini parts
+func deltaParts(chunk providers.StreamChunk) []models.Part {
+	var parts []models.Part
+	if chunk.Thought != "" {
+		parts = append(parts, models.Part{Text: chunk.Thought, Thought: true})
+	}
+	if chunk.Text != "" {
+		parts = append(parts, models.Part{Text: chunk.Text})
+	}
+	return parts
+}
+
+// imageParts converts response images into Gemini fileData parts
+func imageParts(images []providers.Image) []models.Part {
+	var parts []models.Part
+	for _, img := range images {
+		parts = append(parts, models.Part{
+			FileData: &models.FileData{
+				MimeType: imageMIMEType(img.URL),
+				FileURI:  img.URL,
+			},
+		})
+	}
+	return parts
+}
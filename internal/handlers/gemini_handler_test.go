@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newGeminiTestApp(t *testing.T, fake *fakeProvider) *fiber.App {
+	t.Helper()
+	h := NewGeminiHandler(newTestManager(t, fake))
+	app := fiber.New()
+	app.Post("/v1beta/models/:model\\:generateContent", h.HandleV1BetaGenerateContent)
+	app.Post("/v1beta/models/:model\\:streamGenerateContent", h.HandleV1BetaStreamGenerateContent)
+	return app
+}
+
+func TestGeminiGenerateContent(t *testing.T) {
+	fake := &fakeProvider{}
+	app := newGeminiTestApp(t, fake)
+
+	resp, body := post(t, app, "/v1beta/models/test-model:generateContent", `{"contents": [{"role": "user", "parts": [{"text": "Say hello"}]}], "generationConfig": {"candidateCount": 2}}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if len(fake.prompts) != 1 || fake.prompts[0] != "Say hello" {
+		t.Errorf("provider got prompts %q", fake.prompts)
+	}
+
+	var response models.GeminiGenerateResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(response.Candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2: %s", len(response.Candidates), body)
+	}
+	first := response.Candidates[0]
+	if first.FinishReason != "STOP" || first.Content.Role != "model" || len(first.Content.Parts) != 1 || first.Content.Parts[0].Text != "Hello, world" {
+		t.Errorf("unexpected first candidate %+v", first)
+	}
+	if first.GroundingMetadata == nil {
+		t.Errorf("first candidate lacks its grounding metadata")
+	}
+	if second := response.Candidates[1]; second.Index != 1 || second.Content.Parts[0].Text != "Hi" {
+		t.Errorf("unexpected second candidate %+v", second)
+	}
+}
+
+func TestGeminiStreamGenerateContent(t *testing.T) {
+	app := newGeminiTestApp(t, &fakeProvider{})
+
+	resp, body := post(t, app, "/v1beta/models/test-model:streamGenerateContent", `{"contents": [{"parts": [{"text": "Say hello"}]}], "generationConfig": {"thinkingConfig": {"includeThoughts": true}}}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+
+	// The stream is one JSON response per line
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	var thoughts, text strings.Builder
+	finishReason := ""
+	for _, line := range lines {
+		var chunk models.GeminiGenerateResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			t.Fatalf("decode line %q: %v", line, err)
+		}
+		if len(chunk.Candidates) != 1 {
+			t.Fatalf("chunk has %d candidates: %s", len(chunk.Candidates), line)
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Thought {
+				thoughts.WriteString(part.Text)
+			} else {
+				text.WriteString(part.Text)
+			}
+		}
+		finishReason = chunk.Candidates[0].FinishReason
+	}
+	if thoughts.String() != "Thinking." || text.String() != "Hello, world" {
+		t.Errorf("streamed thoughts %q and text %q", thoughts.String(), text.String())
+	}
+	if finishReason != "STOP" {
+		t.Errorf("last chunk has finish reason %q, want STOP", finishReason)
+	}
+}
+
+func TestGeminiErrors(t *testing.T) {
+	app := newGeminiTestApp(t, &fakeProvider{err: providers.ErrTimeout})
+
+	for _, method := range []string{"generateContent", "streamGenerateContent"} {
+		resp, body := post(t, app, "/v1beta/models/test-model:"+method, `{"contents": [{"parts": [{"text": "hi"}]}]}`)
+		if resp.StatusCode != fiber.StatusGatewayTimeout || !strings.Contains(body, `"status":"DEADLINE_EXCEEDED"`) {
+			t.Errorf("%s timeout: status %d, body %s", method, resp.StatusCode, body)
+		}
+	}
+
+	resp, body := post(t, app, "/v1beta/models/gpt-4:generateContent", `{"contents": [{"parts": [{"text": "hi"}]}]}`)
+	if resp.StatusCode != fiber.StatusNotFound || !strings.Contains(body, `"status":"NOT_FOUND"`) {
+		t.Errorf("unknown model: status %d, body %s", resp.StatusCode, body)
+	}
+
+	resp, body = post(t, app, "/v1beta/models/test-model:generateContent", `{"contents": []}`)
+	if resp.StatusCode != fiber.StatusBadRequest || !strings.Contains(body, `"status":"INVALID_ARGUMENT"`) {
+		t.Errorf("empty content: status %d, body %s", resp.StatusCode, body)
+	}
+}
+
+func TestGeminiStreamErrorAfterFirstChunk(t *testing.T) {
+	app := newGeminiTestApp(t, &fakeProvider{streamErr: providers.ErrParseFailure})
+
+	resp, body := post(t, app, "/v1beta/models/test-model:streamGenerateContent", `{"contents": [{"parts": [{"text": "hi"}]}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	var errorLine models.GeminiErrorResponse
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &errorLine); err != nil {
+		t.Fatalf("decode last line: %v", err)
+	}
+	if errorLine.Error.Status != "INTERNAL" || errorLine.Error.Code != fiber.StatusInternalServerError {
+		t.Errorf("stream ends with %s, want an INTERNAL error", lines[len(lines)-1])
+	}
+}
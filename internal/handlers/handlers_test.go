@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// testAnswer is what fakeProvider answers: two candidates, a thought and a citation of "Hello"
+var testAnswer = providers.Response{
+	Text:      "Hello, world",
+	Thoughts:  "Thinking.",
+	Citations: testCitations,
+	Candidates: []providers.Candidate{
+		{ID: "rc_1", Content: "Hello, world", Thoughts: "Thinking.", Citations: testCitations},
+		{ID: "rc_2", Content: "Hi"},
+	},
+}
+
+var testCitations = []providers.Citation{{URL: "https://example.com", Title: "Example", StartIndex: 0, EndIndex: 5}}
+
+// fakeProvider answers with testAnswer, streamed as "Hello" and ", world", or fails with err.
+// streamErr ends a stream after its first text chunk.
+type fakeProvider struct {
+	name      string
+	err       error
+	streamErr error
+	prompts   []string
+}
+
+func (f *fakeProvider) Init(ctx context.Context) error { return nil }
+func (f *fakeProvider) Close() error                   { return nil }
+func (f *fakeProvider) GetName() string                { return f.name }
+func (f *fakeProvider) IsHealthy() bool                { return true }
+func (f *fakeProvider) ListModels() []providers.ModelInfo {
+	return nil
+}
+func (f *fakeProvider) StartChat(options ...providers.ChatOption) providers.ChatSession {
+	return nil
+}
+
+func (f *fakeProvider) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
+	return providers.CollectStream(f.stream(prompt, options))
+}
+
+func (f *fakeProvider) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	return f.stream(prompt, options), nil
+}
+
+func (f *fakeProvider) stream(prompt string, options []providers.GenerateOption) <-chan providers.StreamChunk {
+	f.prompts = append(f.prompts, prompt)
+	var config providers.GenerateConfig
+	for _, opt := range options {
+		opt(&config)
+	}
+
+	chunks := make(chan providers.StreamChunk, 5)
+	defer close(chunks)
+	if f.err != nil {
+		chunks <- providers.StreamChunk{Err: f.err}
+		return chunks
+	}
+	answer := testAnswer
+	if config.IncludeThoughts {
+		chunks <- providers.StreamChunk{Thought: answer.Thoughts}
+	} else {
+		answer.Thoughts = ""
+		answer.Candidates = []providers.Candidate{answer.Candidates[0], answer.Candidates[1]}
+		answer.Candidates[0].Thoughts = ""
+	}
+	chunks <- providers.StreamChunk{Text: "Hello"}
+	if f.streamErr != nil {
+		chunks <- providers.StreamChunk{Err: f.streamErr}
+		return chunks
+	}
+	chunks <- providers.StreamChunk{Text: ", world"}
+	chunks <- providers.StreamChunk{Response: &answer}
+	return chunks
+}
+
+// testRoutes serves test-model from the fake provider and lets fallback-model fall back to it
+const testRoutes = `[
+	{"id": "test-model", "provider": "fake"},
+	{"id": "fallback-model", "provider": "broken", "fallbacks": [{"provider": "fake"}]}
+]`
+
+// newTestManager returns a provider manager over testRoutes with fake registered as "fake"
+func newTestManager(t *testing.T, fake *fakeProvider) *providers.ProviderManager {
+	t.Helper()
+	routesFile := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(routesFile, []byte(testRoutes), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pm, err := providers.NewProviderManager(&config.Config{Models: config.ModelsConfig{RoutesFile: routesFile}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewProviderManager: %v", err)
+	}
+	fake.name = "fake"
+	pm.Register("fake", fake)
+	pm.Register("broken", &fakeProvider{name: "broken", err: providers.ErrRateLimited})
+	return pm
+}
+
+// post sends a JSON body to the app and returns the response with its body read
+func post(t *testing.T, app *fiber.App, path, body string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, string(data)
+}
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	name string
+	data string
+}
+
+// parseSSE splits a server-sent event stream into its events
+func parseSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.data != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Errorf("unexpected line in the event stream: %q", line)
+		}
+	}
+	if current.data != "" {
+		t.Errorf("event %q not terminated by a blank line", current.data)
+	}
+	return events
+}
@@ -5,24 +5,24 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"ai-bridges/internal/models"
 	"ai-bridges/internal/providers"
-	"ai-bridges/internal/providers/gemini"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 type OpenAIHandler struct {
-	client *gemini.Client
-	log    *zap.Logger
+	providers *providers.ProviderManager
+	log       *zap.Logger
 }
 
-func NewOpenAIHandler(client *gemini.Client) *OpenAIHandler {
+func NewOpenAIHandler(pm *providers.ProviderManager) *OpenAIHandler {
 	return &OpenAIHandler{
-		client: client,
-		log:    zap.NewNop(),
+		providers: pm,
+		log:       zap.NewNop(),
 	}
 }
 
@@ -33,20 +33,26 @@ func (h *OpenAIHandler) SetLogger(log *zap.Logger) {
 
 // GetModelData returns raw model data for internal use (e.g. unified list)
 func (h *OpenAIHandler) GetModelData() []models.ModelData {
-	availableModels := h.client.ListModels()
+	availableModels := h.providers.ListModels()
 
 	var data []models.ModelData
 	for _, m := range availableModels {
-		data = append(data, models.ModelData{
-			ID:      m.ID,
-			Object:  "model",
-			Created: m.Created,
-			OwnedBy: m.OwnedBy,
-		})
+		data = append(data, openAIModel(m))
 	}
 	return data
 }
 
+// openAIModel converts a model into an OpenAI model object
+func openAIModel(m providers.ModelInfo) models.ModelData {
+	return models.ModelData{
+		ID:           m.ID,
+		Object:       "model",
+		Created:      m.Created,
+		OwnedBy:      m.OwnedBy,
+		Capabilities: m.Capabilities,
+	}
+}
+
 // HandleModels returns the list of supported models
 func (h *OpenAIHandler) HandleModels(c *fiber.Ctx) error {
 	data := h.GetModelData()
@@ -57,6 +63,15 @@ func (h *OpenAIHandler) HandleModels(c *fiber.Ctx) error {
 	})
 }
 
+// HandleModelByID returns a model of the routing table
+func (h *OpenAIHandler) HandleModelByID(c *fiber.Ctx) error {
+	m, err := h.providers.Model(c.Params("*"))
+	if err != nil {
+		status, body := openAIError(err)
+		return c.Status(status).JSON(body)
+	}
+	return c.JSON(openAIModel(m))
+}
 
 // HandleChatCompletions accepts requests in OpenAI format
 func (h *OpenAIHandler) HandleChatCompletions(c *fiber.Ctx) error {
@@ -71,7 +86,7 @@ func (h *OpenAIHandler) HandleChatCompletions(c *fiber.Ctx) error {
 	}
 
 	// Validate generation parameters
-	if err := validateGenerationRequest(req.Model, req.MaxTokens, req.Temperature); err != nil {
+	if err := validateGenerationRequest(req.Model, req.MaxTokens, req.Temperature, req.N); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(err, "invalid_request_error"))
 	}
 
@@ -81,59 +96,127 @@ func (h *OpenAIHandler) HandleChatCompletions(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(fmt.Errorf("no valid content in messages"), "invalid_request_error"))
 	}
 
+	files, err := collectFiles(req.Messages)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(err, "invalid_request_error"))
+	}
+
+	locale, err := requestLocale(c, req.Locale)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorToResponse(err, "invalid_request_error"))
+	}
+
+	route, err := h.providers.Resolve(req.Model)
+	if err != nil {
+		status, body := openAIError(err)
+		return c.Status(status).JSON(body)
+	}
+
 	opts := []providers.GenerateOption{}
-	if req.Model != "" {
-		opts = append(opts, providers.WithModel(req.Model))
+	if locale != "" {
+		opts = append(opts, providers.WithLocale(locale))
+	}
+	if len(files) > 0 {
+		opts = append(opts, providers.WithFiles(files))
+	}
+	if req.N > 1 {
+		opts = append(opts, providers.WithCandidateCount(req.N))
+	}
+	if req.IncludeReasoning || (req.ReasoningEffort != "" && req.ReasoningEffort != "none") {
+		opts = append(opts, providers.WithThoughts(true))
 	}
 
 	// Handle Streaming
 	if req.Stream {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
+
+		stream, attempt, err := h.providers.GenerateStream(ctx, route, prompt, opts...)
+		if err != nil {
+			cancel()
+			h.log.Error("GenerateContentStream failed", zap.Error(err), zap.String("model", req.Model))
+			status, body := openAIError(err)
+			return c.Status(status).JSON(body)
+		}
+		setFallbackHeader(c, attempt)
+
 		c.Set("Content-Type", "text/event-stream")
 		c.Set("Cache-Control", "no-cache")
 		c.Set("Connection", "keep-alive")
 		c.Set("Transfer-Encoding", "chunked")
 
 		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-			// Add timeout
-			ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 			defer cancel()
 
-			response, err := h.client.GenerateContent(ctx, prompt, opts...)
-			if err != nil {
-				h.log.Error("GenerateContent streaming failed", zap.Error(err), zap.String("model", req.Model))
-				errResponse := errorToResponse(err, "api_error")
-				_ = marshalJSONSafely(h.log, errResponse) // Use safe marshal
-				return
-			}
-
 			id := fmt.Sprintf("chatcmpl-%d", time.Now().Unix())
 			created := time.Now().Unix()
-			chunks := splitResponseIntoChunks(response.Text, 20)
 
-			for i, content := range chunks {
-				chunk := models.ChatCompletionChunk{
+			sendDelta := func(index int, delta models.Delta) error {
+				return sendSSEChunk(w, h.log, "data", models.ChatCompletionChunk{
 					ID:      id,
 					Object:  "chat.completion.chunk",
 					Created: created,
 					Model:   req.Model,
 					Choices: []models.ChunkChoice{
 						{
-							Index: 0,
-							Delta: models.Delta{Content: content},
+							Index: index,
+							Delta: delta,
 						},
 					},
+				})
+			}
+
+			i := 0
+			var final *providers.Response
+			for chunk := range stream {
+				if chunk.Err != nil {
+					h.log.Error("Stream failed", zap.Error(chunk.Err), zap.String("model", req.Model))
+					// OpenAI clients only read data lines, so the error goes in one like upstream sends it
+					_, body := openAIError(chunk.Err)
+					_ = sendSSEChunk(w, h.log, "data", body)
+					return
+				}
+				if chunk.Response != nil {
+					final = chunk.Response
+				}
+				if chunk.Text == "" && chunk.Thought == "" {
+					continue
 				}
 
-				if err := sendSSEChunk(w, h.log, "data", chunk); err != nil {
+				if err := sendDelta(0, models.Delta{Content: chunk.Text, ReasoningContent: chunk.Thought}); err != nil {
 					h.log.Error("Failed to send SSE chunk", zap.Error(err), zap.Int("chunk_index", i))
 					return
 				}
+				i++
+			}
 
-				// Check context cancellation
-				if !sleepWithCancel(c.Context(), 20*time.Millisecond) {
-					h.log.Info("Stream cancelled by client")
-					return
+			// Citations, images and alternative candidates are only known once the final response arrives
+			choices := 1
+			if final != nil {
+				if annotations := urlCitations(final.Text, final.Citations); len(annotations) > 0 {
+					if err := sendDelta(0, models.Delta{Annotations: annotations}); err != nil {
+						h.log.Error("Failed to send SSE chunk", zap.Error(err), zap.Int("chunk_index", i))
+						return
+					}
+				}
+				if len(final.Images) > 0 {
+					if err := sendDelta(0, models.Delta{Content: imagesToMarkdown(final.Images)}); err != nil {
+						h.log.Error("Failed to send SSE chunk", zap.Error(err), zap.Int("chunk_index", i))
+						return
+					}
 				}
+
+				candidates := selectCandidates(final, req.N)
+				for index := 1; index < len(candidates); index++ {
+					if err := sendDelta(index, models.Delta{
+						Content:          candidates[index].Content + imagesToMarkdown(candidates[index].Images),
+						ReasoningContent: candidates[index].Thoughts,
+						Annotations:      urlCitations(candidates[index].Content, candidates[index].Citations),
+					}); err != nil {
+						h.log.Error("Failed to send SSE chunk", zap.Error(err), zap.Int("choice_index", index))
+						return
+					}
+				}
+				choices = len(candidates)
 			}
 
 			// Send final chunk with finish_reason
@@ -142,13 +225,13 @@ func (h *OpenAIHandler) HandleChatCompletions(c *fiber.Ctx) error {
 				Object:  "chat.completion.chunk",
 				Created: created,
 				Model:   req.Model,
-				Choices: []models.ChunkChoice{
-					{
-						Index:        0,
-						Delta:        models.Delta{},
-						FinishReason: "stop",
-					},
-				},
+			}
+			for index := 0; index < choices; index++ {
+				finalChunk.Choices = append(finalChunk.Choices, models.ChunkChoice{
+					Index:        index,
+					Delta:        models.Delta{},
+					FinishReason: "stop",
+				})
 			}
 			_ = sendSSEChunk(w, h.log, "data", finalChunk)
 
@@ -165,31 +248,39 @@ func (h *OpenAIHandler) HandleChatCompletions(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Minute)
 	defer cancel()
 
-	response, err := h.client.GenerateContent(ctx, prompt, opts...)
+	response, attempt, err := h.providers.Generate(ctx, route, prompt, opts...)
 	if err != nil {
 		h.log.Error("GenerateContent failed", zap.Error(err), zap.String("model", req.Model))
-		return c.Status(fiber.StatusInternalServerError).JSON(errorToResponse(err, "api_error"))
+		status, body := openAIError(err)
+		return c.Status(status).JSON(body)
 	}
+	setFallbackHeader(c, attempt)
 
-	return c.JSON(h.convertToOpenAIFormat(response, req.Model))
+	return c.JSON(h.convertToOpenAIFormat(response, req.Model, req.N))
 }
 
-func (h *OpenAIHandler) convertToOpenAIFormat(response *providers.Response, model string) models.ChatCompletionResponse {
+// convertToOpenAIFormat returns up to n candidates of the response as choices
+func (h *OpenAIHandler) convertToOpenAIFormat(response *providers.Response, model string, n int) models.ChatCompletionResponse {
+	var choices []models.Choice
+	for i, candidate := range selectCandidates(response, n) {
+		choices = append(choices, models.Choice{
+			Index: i,
+			Message: models.Message{
+				Role:             "assistant",
+				Content:          candidate.Content + imagesToMarkdown(candidate.Images),
+				ReasoningContent: candidate.Thoughts,
+				Annotations:      urlCitations(candidate.Content, candidate.Citations),
+			},
+			FinishReason: "stop",
+		})
+	}
+
 	return models.ChatCompletionResponse{
 		ID:      fmt.Sprintf("chatcmpl-%d", time.Now().Unix()),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
 		Model:   model,
-		Choices: []models.Choice{
-			{
-				Index: 0,
-				Message: models.Message{
-					Role:    "assistant",
-					Content: response.Text,
-				},
-				FinishReason: "stop",
-			},
-		},
+		Choices: choices,
 		Usage: models.Usage{
 			PromptTokens:     0,
 			CompletionTokens: 0,
@@ -197,3 +288,22 @@ func (h *OpenAIHandler) convertToOpenAIFormat(response *providers.Response, mode
 		},
 	}
 }
+
+// urlCitations converts citations of text into OpenAI url_citation annotations,
+// whose indexes count characters rather than bytes
+func urlCitations(text string, citations []providers.Citation) []models.Annotation {
+	var annotations []models.Annotation
+	for _, citation := range citations {
+		start, end := citationSpan(text, citation)
+		annotations = append(annotations, models.Annotation{
+			Type: "url_citation",
+			URLCitation: &models.URLCitation{
+				URL:        citation.URL,
+				Title:      citation.Title,
+				StartIndex: utf8.RuneCountInString(text[:start]),
+				EndIndex:   utf8.RuneCountInString(text[:end]),
+			},
+		})
+	}
+	return annotations
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newOpenAITestApp(t *testing.T, fake *fakeProvider) *fiber.App {
+	t.Helper()
+	h := NewOpenAIHandler(newTestManager(t, fake))
+	app := fiber.New()
+	app.Post("/v1/chat/completions", h.HandleChatCompletions)
+	app.Get("/v1/models/*", h.HandleModelByID)
+	return app
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	fake := &fakeProvider{}
+	app := newOpenAITestApp(t, fake)
+
+	resp, body := post(t, app, "/v1/chat/completions", `{"model": "test-model", "n": 2, "messages": [{"role": "user", "content": "Say hello"}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if len(fake.prompts) != 1 || !strings.Contains(fake.prompts[0], "Say hello") {
+		t.Errorf("provider got prompts %q", fake.prompts)
+	}
+
+	var completion models.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(body), &completion); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if completion.Object != "chat.completion" || completion.Model != "test-model" || len(completion.Choices) != 2 {
+		t.Fatalf("unexpected completion %s", body)
+	}
+	first := completion.Choices[0]
+	if first.Message.Role != "assistant" || first.Message.Content != "Hello, world" || first.FinishReason != "stop" {
+		t.Errorf("unexpected first choice %+v", first)
+	}
+	// Message decodes like a request and drops annotations, so they are checked in the body
+	if !strings.Contains(body, `"annotations":[{"type":"url_citation","url_citation":{"url":"https://example.com","title":"Example","start_index":0,"end_index":5}}]`) {
+		t.Errorf("first choice lacks its citation: %s", body)
+	}
+	if completion.Choices[1].Message.Content != "Hi" {
+		t.Errorf("unexpected second choice %+v", completion.Choices[1])
+	}
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	app := newOpenAITestApp(t, &fakeProvider{})
+
+	resp, body := post(t, app, "/v1/chat/completions", `{"model": "test-model", "stream": true, "include_reasoning": true, "messages": [{"role": "user", "content": "Say hello"}]}`)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := parseSSE(t, body)
+	if len(events) == 0 || events[len(events)-1].data != "[DONE]" {
+		t.Fatalf("stream does not end with [DONE]: %s", body)
+	}
+
+	var content, reasoning strings.Builder
+	finishReason := ""
+	for _, event := range events[:len(events)-1] {
+		var chunk models.ChatCompletionChunk
+		if err := json.Unmarshal([]byte(event.data), &chunk); err != nil {
+			t.Fatalf("decode chunk %q: %v", event.data, err)
+		}
+		if chunk.Object != "chat.completion.chunk" || chunk.Model != "test-model" || len(chunk.Choices) != 1 {
+			t.Errorf("unexpected chunk %s", event.data)
+			continue
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		reasoning.WriteString(chunk.Choices[0].Delta.ReasoningContent)
+		if chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+	}
+	if content.String() != "Hello, world" || reasoning.String() != "Thinking." {
+		t.Errorf("streamed content %q and reasoning %q", content.String(), reasoning.String())
+	}
+	if finishReason != "stop" {
+		t.Errorf("finish reason %q, want stop", finishReason)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	app := newOpenAITestApp(t, &fakeProvider{err: providers.ErrRateLimited})
+
+	resp, body := post(t, app, "/v1/chat/completions", `{"model": "test-model", "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusTooManyRequests || !strings.Contains(body, `"type":"rate_limit_error"`) {
+		t.Errorf("rate limit: status %d, body %s", resp.StatusCode, body)
+	}
+
+	// A stream that fails before its first chunk is still answered with an error status
+	resp, body = post(t, app, "/v1/chat/completions", `{"model": "test-model", "stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("stream rate limit: status %d, body %s", resp.StatusCode, body)
+	}
+
+	resp, body = post(t, app, "/v1/chat/completions", `{"model": "gpt-4", "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusNotFound || !strings.Contains(body, `"code":"model_not_found"`) {
+		t.Errorf("unknown model: status %d, body %s", resp.StatusCode, body)
+	}
+}
+
+func TestOpenAIStreamErrorAfterFirstChunk(t *testing.T) {
+	app := newOpenAITestApp(t, &fakeProvider{streamErr: providers.ErrUpstreamUnavailable})
+
+	resp, body := post(t, app, "/v1/chat/completions", `{"model": "test-model", "stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	events := parseSSE(t, body)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want a chunk and an error: %s", len(events), body)
+	}
+	var errorEvent struct {
+		Error models.Error `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(events[1].data), &errorEvent); err != nil {
+		t.Fatalf("decode error event: %v", err)
+	}
+	if errorEvent.Error.Code != "upstream_unavailable" {
+		t.Errorf("unexpected error event %s", events[1].data)
+	}
+}
+
+func TestOpenAIFallbackHeader(t *testing.T) {
+	app := newOpenAITestApp(t, &fakeProvider{})
+
+	resp, body := post(t, app, "/v1/chat/completions", `{"model": "fallback-model", "messages": [{"role": "user", "content": "hi"}]}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get(fallbackHeader); got != "1; provider=fake; model=fallback-model" {
+		t.Errorf("%s = %q", fallbackHeader, got)
+	}
+}
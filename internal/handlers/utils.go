@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"bufio"
-	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
+	"path/filepath"
 	"strings"
-	"time"
 
+	"ai-bridges/internal/config"
 	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
 
+	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
@@ -42,7 +47,7 @@ func validateMessages(messages []models.Message) error {
 
 	allEmpty := true
 	for _, msg := range messages {
-		if strings.TrimSpace(msg.Content) != "" {
+		if strings.TrimSpace(msg.Content) != "" || len(msg.Parts) > 0 {
 			allEmpty = false
 			break
 		}
@@ -55,12 +60,180 @@ func validateMessages(messages []models.Message) error {
 	return nil
 }
 
+// collectFiles extracts the attachments (images, PDFs, text files) from message parts
+func collectFiles(messages []models.Message) ([]providers.File, error) {
+	var files []providers.File
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			var (
+				file providers.File
+				err  error
+			)
+
+			switch {
+			case part.ImageURL != nil:
+				file, err = fileFromDataURL(part.ImageURL.URL, "")
+			case part.File != nil:
+				file, err = fileFromDataURL(part.File.FileData, part.File.Filename)
+			case part.Source != nil:
+				file, err = fileFromSource(part.Source)
+			default:
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if file.Name == "" {
+				file.Name = defaultFileName(len(files), file.MIMEType)
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// collectGeminiFiles extracts inline data parts from Gemini contents
+func collectGeminiFiles(contents []models.Content) ([]providers.File, error) {
+	var files []providers.File
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			if part.InlineData == nil {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid inlineData: %w", err)
+			}
+			files = append(files, providers.File{
+				Name:     defaultFileName(len(files), part.InlineData.MimeType),
+				MIMEType: part.InlineData.MimeType,
+				Data:     data,
+			})
+		}
+	}
+	return files, nil
+}
+
+// fileFromDataURL decodes a "data:<mime>;base64,<data>" URL. Bare base64 is accepted
+// as well, with the MIME type guessed from the file name.
+func fileFromDataURL(value, name string) (providers.File, error) {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return providers.File{}, fmt.Errorf("remote file URLs are not supported, send the content as a base64 data URL")
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(name))
+	payload := value
+	if rest, ok := strings.CutPrefix(value, "data:"); ok {
+		header, data, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return providers.File{}, fmt.Errorf("invalid data URL: only base64 data URLs are supported")
+		}
+		mimeType = strings.TrimSuffix(header, ";base64")
+		payload = data
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return providers.File{}, fmt.Errorf("invalid base64 file data: %w", err)
+	}
+	return providers.File{Name: name, MIMEType: mimeType, Data: data}, nil
+}
+
+// fileFromSource decodes a Claude image or document source
+func fileFromSource(source *models.ContentSource) (providers.File, error) {
+	switch source.Type {
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(source.Data)
+		if err != nil {
+			return providers.File{}, fmt.Errorf("invalid base64 source data: %w", err)
+		}
+		return providers.File{MIMEType: source.MediaType, Data: data}, nil
+	case "text":
+		mimeType := source.MediaType
+		if mimeType == "" {
+			mimeType = "text/plain"
+		}
+		return providers.File{MIMEType: mimeType, Data: []byte(source.Data)}, nil
+	default:
+		return providers.File{}, fmt.Errorf("unsupported content source type %q, send the content as base64", source.Type)
+	}
+}
+
+// defaultFileName names the n-th attachment after its MIME type
+func defaultFileName(n int, mimeType string) string {
+	ext := ".bin"
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+		// Prefer the extension named after the subtype (".jpeg" over ".jfif")
+		if _, subtype, ok := strings.Cut(mimeType, "/"); ok {
+			for _, e := range exts {
+				if e == "."+subtype {
+					ext = e
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("file-%d%s", n+1, ext)
+}
+
+// selectCandidates returns up to n candidates of a response (at least one). Providers
+// that report no candidates yield a single candidate built from the response itself.
+func selectCandidates(response *providers.Response, n int) []providers.Candidate {
+	candidates := response.Candidates
+	if len(candidates) == 0 {
+		candidates = []providers.Candidate{{Content: response.Text, Images: response.Images, Citations: response.Citations}}
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n < len(candidates) {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// citationSpan returns the byte span of text a citation supports; unspanned or out-of-range
+// citations support the whole text
+func citationSpan(text string, citation providers.Citation) (int, int) {
+	if citation.StartIndex < 0 || citation.EndIndex <= citation.StartIndex || citation.EndIndex > len(text) {
+		return 0, len(text)
+	}
+	return citation.StartIndex, citation.EndIndex
+}
+
+// imagesToMarkdown renders response images as markdown for text-only protocols
+func imagesToMarkdown(images []providers.Image) string {
+	var sb strings.Builder
+	for _, img := range images {
+		alt := img.AltText
+		if alt == "" {
+			alt = img.Title
+		}
+		sb.WriteString(fmt.Sprintf("\n\n![%s](%s)", alt, img.URL))
+	}
+	return sb.String()
+}
+
+// imageMIMEType guesses an image MIME type from its URL
+func imageMIMEType(url string) string {
+	ext := filepath.Ext(strings.SplitN(url, "?", 2)[0])
+	if mimeType := mime.TypeByExtension(ext); strings.HasPrefix(mimeType, "image/") {
+		return mimeType
+	}
+	return "image/png"
+}
+
 // validateGenerationRequest validates common generation request parameters
-func validateGenerationRequest(model string, maxTokens int, temperature float32) error {
+func validateGenerationRequest(model string, maxTokens int, temperature float32, candidateCount int) error {
 	if maxTokens < 0 {
 		return fmt.Errorf("max_tokens must be non-negative")
 	}
 
+	if candidateCount < 0 {
+		return fmt.Errorf("candidate count must be non-negative")
+	}
+
 	if temperature < 0 || temperature > 2 {
 		return fmt.Errorf("temperature must be between 0 and 2")
 	}
@@ -68,6 +241,41 @@ func validateGenerationRequest(model string, maxTokens int, temperature float32)
 	return nil
 }
 
+// fallbackHeader reports which fallback of the model's route served a request
+const fallbackHeader = "X-Bridge-Fallback"
+
+// setFallbackHeader sets the fallback header when a request was not served by the route's own
+// provider, e.g. "1; provider=gemini; model=gemini-2.5-flash; account=2"
+func setFallbackHeader(c *fiber.Ctx, attempt providers.Attempt) {
+	if attempt.Fallback == 0 {
+		return
+	}
+	value := fmt.Sprintf("%d; provider=%s; model=%s", attempt.Fallback, attempt.Provider, attempt.Model)
+	if attempt.Account != 0 {
+		value += fmt.Sprintf("; account=%d", attempt.Account)
+	}
+	c.Set(fallbackHeader, value)
+}
+
+// localeHeader overrides the locale of the upstream account for one request
+const localeHeader = "X-Gemini-Locale"
+
+// requestLocale returns the locale set in the request body field or, failing that, in the
+// X-Gemini-Locale header. An empty locale keeps the account default.
+func requestLocale(c *fiber.Ctx, field string) (string, error) {
+	locale := strings.TrimSpace(field)
+	if locale == "" {
+		locale = strings.TrimSpace(c.Get(localeHeader))
+	}
+	if locale == "" {
+		return "", nil
+	}
+	if err := config.ValidateLocale(locale); err != nil {
+		return "", fmt.Errorf("invalid locale: %w", err)
+	}
+	return locale, nil
+}
+
 // marshalJSONSafely marshals JSON and logs errors instead of silently failing
 func marshalJSONSafely(log *zap.Logger, v interface{}) []byte {
 	data, err := json.Marshal(v)
@@ -110,27 +318,82 @@ func sendSSEChunk(w *bufio.Writer, log *zap.Logger, event string, chunk interfac
 	return nil
 }
 
-// splitResponseIntoChunks simulates streaming by splitting response into chunks
-func splitResponseIntoChunks(text string, delayMs int) []string {
-	words := strings.Split(text, " ")
-	var chunks []string
-	for i, word := range words {
-		content := word
-		if i < len(words)-1 {
-			content += " "
+// statusOverloaded is the non-standard status Anthropic uses for overloaded_error
+const statusOverloaded = 529
+
+// errorMapping describes how one class of provider error is reported in each protocol
+type errorMapping struct {
+	target error
+
+	openAIStatus int
+	openAIType   string
+	openAICode   string
+
+	claudeStatus int
+	claudeType   string
+
+	geminiStatus int
+	geminiCode   string
+}
+
+// errorMappings follows the status codes and error types each official API uses,
+// so client SDKs apply their usual retry behaviour. Expired upstream credentials are the
+// bridge's problem, not the client's, so they are reported as an unavailable upstream
+// rather than as an invalid API key.
+var errorMappings = []errorMapping{
+	{providers.ErrUnknownModel, fiber.StatusNotFound, "invalid_request_error", "model_not_found", fiber.StatusNotFound, "not_found_error", fiber.StatusNotFound, "NOT_FOUND"},
+	{providers.ErrAuthExpired, fiber.StatusServiceUnavailable, "api_error", "upstream_auth_expired", fiber.StatusServiceUnavailable, "api_error", fiber.StatusServiceUnavailable, "UNAVAILABLE"},
+	{providers.ErrContentBlocked, fiber.StatusBadRequest, "invalid_request_error", "content_policy_violation", fiber.StatusBadRequest, "invalid_request_error", fiber.StatusBadRequest, "INVALID_ARGUMENT"},
+	{providers.ErrRateLimited, fiber.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded", fiber.StatusTooManyRequests, "rate_limit_error", fiber.StatusTooManyRequests, "RESOURCE_EXHAUSTED"},
+	{providers.ErrUpstreamUnavailable, fiber.StatusBadGateway, "api_error", "upstream_unavailable", statusOverloaded, "overloaded_error", fiber.StatusServiceUnavailable, "UNAVAILABLE"},
+	{providers.ErrParseFailure, fiber.StatusBadGateway, "api_error", "upstream_parse_error", fiber.StatusBadGateway, "api_error", fiber.StatusInternalServerError, "INTERNAL"},
+	{providers.ErrTimeout, fiber.StatusGatewayTimeout, "timeout_error", "upstream_timeout", fiber.StatusGatewayTimeout, "timeout_error", fiber.StatusGatewayTimeout, "DEADLINE_EXCEEDED"},
+}
+
+// defaultErrorMapping is used for errors outside the provider taxonomy
+var defaultErrorMapping = errorMapping{nil, fiber.StatusInternalServerError, "api_error", "", fiber.StatusInternalServerError, "api_error", fiber.StatusInternalServerError, "INTERNAL"}
+
+// classifyError finds the mapping for a provider error
+func classifyError(err error) errorMapping {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			return m
 		}
-		chunks = append(chunks, content)
 	}
-	return chunks
+	return defaultErrorMapping
+}
+
+// openAIError converts a provider error to an OpenAI status code and error body
+func openAIError(err error) (int, models.ErrorResponse) {
+	m := classifyError(err)
+	return m.openAIStatus, models.ErrorResponse{
+		Error: models.Error{
+			Message: err.Error(),
+			Type:    m.openAIType,
+			Code:    m.openAICode,
+		},
+	}
+}
+
+// claudeError converts a provider error to a Claude status code and error body
+func claudeError(err error) (int, fiber.Map) {
+	m := classifyError(err)
+	return m.claudeStatus, fiber.Map{
+		"type":  "error",
+		"error": fiber.Map{"type": m.claudeType, "message": err.Error()},
+	}
+}
+
+// geminiError converts a provider error to a Gemini status code and error body
+func geminiError(err error) (int, models.GeminiErrorResponse) {
+	m := classifyError(err)
+	return m.geminiStatus, geminiErrorBody(m.geminiStatus, m.geminiCode, err)
 }
 
-// sleepWithCancel sleeps for the specified duration or until context is cancelled
-func sleepWithCancel(ctx context.Context, duration time.Duration) bool {
-	select {
-	case <-time.After(duration):
-		return true
-	case <-ctx.Done():
-		return false
+// geminiErrorBody builds an error body in the Gemini API format
+func geminiErrorBody(code int, status string, err error) models.GeminiErrorResponse {
+	return models.GeminiErrorResponse{
+		Error: models.GeminiError{Code: code, Message: err.Error(), Status: status},
 	}
 }
 
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"ai-bridges/internal/models"
+	"ai-bridges/internal/providers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSetFallbackHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt providers.Attempt
+		want    string
+	}{
+		{"route's own provider", providers.Attempt{Target: providers.Target{Provider: "gemini", Model: "gemini-2.5-pro"}}, ""},
+		{"fallback", providers.Attempt{Target: providers.Target{Provider: "gemini-api", Model: "gemini-2.5-flash"}, Fallback: 1}, "1; provider=gemini-api; model=gemini-2.5-flash"},
+		{"pooled account", providers.Attempt{Target: providers.Target{Provider: "gemini", Model: "unspecified", Account: 2}, Fallback: 2}, "2; provider=gemini; model=unspecified; account=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				setFallbackHeader(c, tt.attempt)
+				return nil
+			})
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Header.Get(fallbackHeader); got != tt.want {
+				t.Errorf("%s = %q, want %q", fallbackHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tests := []struct {
+		err error
+
+		openAIStatus int
+		openAIType   string
+		openAICode   string
+		claudeStatus int
+		claudeType   string
+		geminiStatus int
+		geminiCode   string
+	}{
+		{providers.ErrUnknownModel, 404, "invalid_request_error", "model_not_found", 404, "not_found_error", 404, "NOT_FOUND"},
+		{providers.ErrAuthExpired, 503, "api_error", "upstream_auth_expired", 503, "api_error", 503, "UNAVAILABLE"},
+		{providers.ErrContentBlocked, 400, "invalid_request_error", "content_policy_violation", 400, "invalid_request_error", 400, "INVALID_ARGUMENT"},
+		{providers.ErrRateLimited, 429, "rate_limit_error", "rate_limit_exceeded", 429, "rate_limit_error", 429, "RESOURCE_EXHAUSTED"},
+		{providers.ErrUpstreamUnavailable, 502, "api_error", "upstream_unavailable", 529, "overloaded_error", 503, "UNAVAILABLE"},
+		{providers.ErrParseFailure, 502, "api_error", "upstream_parse_error", 502, "api_error", 500, "INTERNAL"},
+		{providers.ErrTimeout, 504, "timeout_error", "upstream_timeout", 504, "timeout_error", 504, "DEADLINE_EXCEEDED"},
+		{errors.New("something else"), 500, "api_error", "", 500, "api_error", 500, "INTERNAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			// Providers wrap the sentinels with details
+			err := fmt.Errorf("account 2: %w", tt.err)
+
+			status, body := openAIError(err)
+			openAI, _ := body.Error.(models.Error)
+			if status != tt.openAIStatus || openAI.Type != tt.openAIType || openAI.Code != tt.openAICode {
+				t.Errorf("OpenAI: %d %+v, want %d %s/%s", status, body.Error, tt.openAIStatus, tt.openAIType, tt.openAICode)
+			}
+			if openAI.Message != err.Error() {
+				t.Errorf("OpenAI message %q", openAI.Message)
+			}
+
+			status, claude := claudeError(err)
+			inner, _ := claude["error"].(fiber.Map)
+			if status != tt.claudeStatus || claude["type"] != "error" || inner["type"] != tt.claudeType {
+				t.Errorf("Claude: %d %v, want %d %s", status, claude, tt.claudeStatus, tt.claudeType)
+			}
+			if inner["message"] != err.Error() {
+				t.Errorf("Claude message %v", inner["message"])
+			}
+
+			status, gemini := geminiError(err)
+			if status != tt.geminiStatus || gemini.Error.Code != tt.geminiStatus || gemini.Error.Status != tt.geminiCode {
+				t.Errorf("Gemini: %d %+v, want %d %s", status, gemini.Error, tt.geminiStatus, tt.geminiCode)
+			}
+			if gemini.Error.Message != err.Error() {
+				t.Errorf("Gemini message %q", gemini.Error.Message)
+			}
+		})
+	}
+
+	// Every sentinel has a mapping of its own
+	for _, m := range errorMappings {
+		if classifyError(m.target).target != m.target {
+			t.Errorf("%v is shadowed by another mapping", m.target)
+		}
+	}
+}
+
+func TestCitationSpan(t *testing.T) {
+	text := "Paris is the capital of France."
+	tests := []struct {
+		name       string
+		citation   providers.Citation
+		start, end int
+	}{
+		{"span", providers.Citation{StartIndex: 0, EndIndex: 5}, 0, 5},
+		{"inner span", providers.Citation{StartIndex: 13, EndIndex: 30}, 13, 30},
+		{"unspanned", providers.Citation{}, 0, len(text)},
+		{"empty span", providers.Citation{StartIndex: 4, EndIndex: 4}, 0, len(text)},
+		{"reversed span", providers.Citation{StartIndex: 10, EndIndex: 4}, 0, len(text)},
+		{"end past the text", providers.Citation{StartIndex: 13, EndIndex: 100}, 0, len(text)},
+		{"negative start", providers.Citation{StartIndex: -3, EndIndex: 5}, 0, len(text)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := citationSpan(text, tt.citation)
+			if start != tt.start || end != tt.end {
+				t.Errorf("span [%d, %d), want [%d, %d)", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
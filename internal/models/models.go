@@ -1,9 +1,84 @@
 package models
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 // Message represents a chat message (shared across OpenAI, Claude, etc)
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string        `json:"role"`
+	Content          string        `json:"content"`
+	ReasoningContent string        `json:"reasoning_content,omitempty"` // OpenAI-style reasoning in responses
+	Annotations      []Annotation  `json:"annotations,omitempty"`       // OpenAI url_citation sources in responses
+	Parts            []ContentPart `json:"-"`                           // set when content was sent as an array of parts
+}
+
+// UnmarshalJSON accepts content either as a plain string or as an array of parts.
+// For arrays, the text parts are joined into Content and all parts are kept in Parts.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	m.Role = raw.Role
+	m.Content = ""
+	m.Parts = nil
+
+	content := strings.TrimSpace(string(raw.Content))
+	if content == "" || content == "null" {
+		return nil
+	}
+	if !strings.HasPrefix(content, "[") {
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+
+	if err := json.Unmarshal(raw.Content, &m.Parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == "text" && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+// ContentPart is one element of an array-valued message content.
+// It covers OpenAI parts ("text", "image_url", "file") and Claude blocks ("text", "image", "document").
+type ContentPart struct {
+	Type     string         `json:"type"`
+	Text     string         `json:"text,omitempty"`
+	ImageURL *ImageURL      `json:"image_url,omitempty"`
+	File     *ContentFile   `json:"file,omitempty"`
+	Source   *ContentSource `json:"source,omitempty"`
+}
+
+// ImageURL is an OpenAI image reference, usually a base64 data URL
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ContentFile is an OpenAI inline file
+type ContentFile struct {
+	FileData string `json:"file_data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// ContentSource is the source of a Claude image or document block
+type ContentSource struct {
+	Type      string `json:"type"` // "base64", "url" or "text"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 // ModelListResponse represents the list of models
@@ -21,14 +96,35 @@ type ModelData struct {
 	CreatedAt   int64  `json:"created_at,omitempty"`
 	OwnedBy     string `json:"owned_by,omitempty"`
 	DisplayName string `json:"display_name,omitempty"`
+
+	Capabilities []string `json:"capabilities,omitempty"` // "thinking", "images", "files", "search"
 }
 
 // Delta represents the delta content in a chunk
 type Delta struct {
-	Type    string `json:"type,omitempty"`    // "text_delta"
-	Content string `json:"content,omitempty"` // for OpenAI
-	Text    string `json:"text,omitempty"`    // for Claude
-	Role    string `json:"role,omitempty"`
+	Type             string `json:"type,omitempty"`              // "text_delta" or "thinking_delta"
+	Content          string `json:"content,omitempty"`           // for OpenAI
+	ReasoningContent string `json:"reasoning_content,omitempty"` // for OpenAI
+	Text             string `json:"text,omitempty"`              // for Claude
+	Thinking         string `json:"thinking,omitempty"`          // for Claude
+	Role             string `json:"role,omitempty"`
+
+	Annotations []Annotation  `json:"annotations,omitempty"` // for OpenAI
+	Citation    *TextCitation `json:"citation,omitempty"`    // for Claude "citations_delta"
+}
+
+// Annotation is an OpenAI message annotation
+type Annotation struct {
+	Type        string       `json:"type"` // "url_citation"
+	URLCitation *URLCitation `json:"url_citation,omitempty"`
+}
+
+// URLCitation cites a web page. The indexes are character offsets into the message content.
+type URLCitation struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
 }
 
 // Usage represents token usage (compatible format)
@@ -63,6 +159,12 @@ type ChatCompletionRequest struct {
 	Stream      bool      `json:"stream,omitempty"`
 	Temperature float32   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	N           int       `json:"n,omitempty"`
+	// ReasoningEffort or the IncludeReasoning extension request reasoning_content in the response
+	ReasoningEffort  string `json:"reasoning_effort,omitempty"`
+	IncludeReasoning bool   `json:"include_reasoning,omitempty"`
+	// Locale is a bridge extension selecting the language of the answer, e.g. "pt-BR"
+	Locale string `json:"locale,omitempty"`
 }
 
 // ChatCompletionResponse represents OpenAI chat completion response
@@ -102,28 +204,89 @@ type ChunkChoice struct {
 
 // MessageRequest represents the specialized Claude request body
 type MessageRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
-	System    string    `json:"system,omitempty"`
-	Stream    bool      `json:"stream,omitempty"`
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	Messages  []Message       `json:"messages"`
+	System    string          `json:"system,omitempty"`
+	Stream    bool            `json:"stream,omitempty"`
+	Thinking  *ThinkingConfig `json:"thinking,omitempty"`
+	// CandidateCount is a bridge extension: when above 1, non-streaming responses
+	// also list alternative candidates in MessageResponse.Candidates. Streaming
+	// requests cannot ask for more than one candidate.
+	CandidateCount int `json:"candidate_count,omitempty"`
+	// Locale is a bridge extension selecting the language of the answer, e.g. "pt-BR"
+	Locale string `json:"locale,omitempty"`
+}
+
+// ThinkingConfig enables extended thinking in a Claude request
+type ThinkingConfig struct {
+	Type         string `json:"type"` // "enabled" or "disabled"
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
 }
 
 // MessageResponse represents the non-streaming response body
 type MessageResponse struct {
-	ID         string          `json:"id"`
-	Type       string          `json:"type"` // "message"
-	Role       string          `json:"role"` // "assistant"
-	Model      string          `json:"model"`
-	Content    []ConfigContent `json:"content"`
-	StopReason string          `json:"stop_reason"`
-	Usage      Usage           `json:"usage"`
+	ID         string             `json:"id"`
+	Type       string             `json:"type"` // "message"
+	Role       string             `json:"role"` // "assistant"
+	Model      string             `json:"model"`
+	Content    []ConfigContent    `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      Usage              `json:"usage"`
+	Candidates []MessageCandidate `json:"candidates,omitempty"` // bridge extension, see MessageRequest.CandidateCount
+}
+
+// MessageCandidate is an alternative response returned by the candidate_count extension
+type MessageCandidate struct {
+	Index   int             `json:"index"`
+	Content []ConfigContent `json:"content"`
 }
 
 // ConfigContent represents the content block in a response
 type ConfigContent struct {
-	Type string `json:"type"` // "text"
-	Text string `json:"text"`
+	Type      string         `json:"type"` // "text", "thinking" or "image"
+	Text      string         `json:"text"`
+	Thinking  string         `json:"thinking,omitempty"`
+	Signature string         `json:"signature,omitempty"`
+	Source    *ImageSource   `json:"source,omitempty"`
+	Citations []TextCitation `json:"citations,omitempty"`
+}
+
+// TextCitation is a web source cited by a Claude text block
+type TextCitation struct {
+	Type      string `json:"type"` // "web_search_result_location"
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	CitedText string `json:"cited_text"`
+}
+
+// MarshalJSON emits only the fields of the block's type, keeping required ones even when empty
+func (c ConfigContent) MarshalJSON() ([]byte, error) {
+	switch c.Type {
+	case "text":
+		return json.Marshal(struct {
+			Type      string         `json:"type"`
+			Text      string         `json:"text"`
+			Citations []TextCitation `json:"citations,omitempty"`
+		}{c.Type, c.Text, c.Citations})
+	case "thinking":
+		return json.Marshal(struct {
+			Type      string `json:"type"`
+			Thinking  string `json:"thinking"`
+			Signature string `json:"signature"`
+		}{c.Type, c.Thinking, c.Signature})
+	default:
+		return json.Marshal(struct {
+			Type   string       `json:"type"`
+			Source *ImageSource `json:"source,omitempty"`
+		}{c.Type, c.Source})
+	}
+}
+
+// ImageSource is the source of an image block in a Claude response
+type ImageSource struct {
+	Type string `json:"type"` // "url"
+	URL  string `json:"url"`
 }
 
 // StreamEvent represents a streaming event
@@ -151,13 +314,16 @@ type GeminiModel struct {
 	Description                string   `json:"description,omitempty"`
 	Version                    string   `json:"version,omitempty"`
 	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	Thinking                   bool     `json:"thinking,omitempty"`
 }
 
 // GeminiGenerateRequest represents a Gemini generate request
 type GeminiGenerateRequest struct {
-	Contents        []Content             `json:"contents"`
-	GenerationConfig *GenerationConfig    `json:"generationConfig,omitempty"`
-	Safety           []map[string]string  `json:"safety_settings,omitempty"`
+	Contents         []Content           `json:"contents"`
+	GenerationConfig *GenerationConfig   `json:"generationConfig,omitempty"`
+	Safety           []map[string]string `json:"safety_settings,omitempty"`
+	// Locale is a bridge extension selecting the language of the answer, e.g. "pt-BR"
+	Locale string `json:"locale,omitempty"`
 }
 
 // Content represents a content block in Gemini API
@@ -169,7 +335,15 @@ type Content struct {
 // Part represents a part of content
 type Part struct {
 	Text       string      `json:"text,omitempty"`
+	Thought    bool        `json:"thought,omitempty"` // marks the text as the model's reasoning
 	InlineData *InlineData `json:"inlineData,omitempty"`
+	FileData   *FileData   `json:"fileData,omitempty"`
+}
+
+// FileData references content by URI (e.g., images returned by the model)
+type FileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 // InlineData represents inline data (e.g., images)
@@ -180,24 +354,78 @@ type InlineData struct {
 
 // GenerationConfig represents generation configuration
 type GenerationConfig struct {
-	Temperature     float32 `json:"temperature,omitempty"`
-	TopP            float32 `json:"topP,omitempty"`
-	TopK            int32   `json:"topK,omitempty"`
-	MaxOutputTokens int32   `json:"maxOutputTokens,omitempty"`
+	Temperature     float32               `json:"temperature,omitempty"`
+	TopP            float32               `json:"topP,omitempty"`
+	TopK            int32                 `json:"topK,omitempty"`
+	MaxOutputTokens int32                 `json:"maxOutputTokens,omitempty"`
+	CandidateCount  int32                 `json:"candidateCount,omitempty"`
+	ThinkingConfig  *ThinkingConfigGemini `json:"thinkingConfig,omitempty"`
+}
+
+// ThinkingConfigGemini controls reasoning output in a Gemini request
+type ThinkingConfigGemini struct {
+	IncludeThoughts bool  `json:"includeThoughts,omitempty"`
+	ThinkingBudget  int32 `json:"thinkingBudget,omitempty"`
 }
 
 // GeminiGenerateResponse represents a Gemini generate response
 type GeminiGenerateResponse struct {
-	Candidates   []Candidate    `json:"candidates"`
+	Candidates    []Candidate    `json:"candidates"`
 	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
 }
 
 // Candidate represents a candidate response
 type Candidate struct {
-	Index        int    `json:"index"`
-	Content      Content `json:"content"`
-	FinishReason string `json:"finishReason,omitempty"`
-	FinishMessage string `json:"finishMessage,omitempty"`
+	Index         int     `json:"index"`
+	Content       Content `json:"content"`
+	FinishReason  string  `json:"finishReason,omitempty"`
+	FinishMessage string  `json:"finishMessage,omitempty"`
+
+	GroundingMetadata *GroundingMetadata `json:"groundingMetadata,omitempty"`
+	CitationMetadata  *CitationMetadata  `json:"citationMetadata,omitempty"`
+}
+
+// GroundingMetadata lists the web sources a candidate is grounded in and the parts of
+// the text each one supports
+type GroundingMetadata struct {
+	GroundingChunks   []GroundingChunk   `json:"groundingChunks"`
+	GroundingSupports []GroundingSupport `json:"groundingSupports,omitempty"`
+}
+
+// GroundingChunk is one grounding source
+type GroundingChunk struct {
+	Web *WebSource `json:"web,omitempty"`
+}
+
+// WebSource is a web page used for grounding
+type WebSource struct {
+	URI   string `json:"uri"`
+	Title string `json:"title,omitempty"`
+}
+
+// GroundingSupport ties a segment of the text to the grounding chunks backing it
+type GroundingSupport struct {
+	Segment               Segment `json:"segment"`
+	GroundingChunkIndices []int   `json:"groundingChunkIndices"`
+}
+
+// Segment is a span of the candidate text; indexes are byte offsets
+type Segment struct {
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex"`
+	Text       string `json:"text,omitempty"`
+}
+
+// CitationMetadata lists the sources cited by a candidate
+type CitationMetadata struct {
+	CitationSources []CitationSource `json:"citationSources"`
+}
+
+// CitationSource is one cited source; indexes are byte offsets into the candidate text
+type CitationSource struct {
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex,omitempty"`
+	URI        string `json:"uri"`
 }
 
 // UsageMetadata represents usage metadata
@@ -207,6 +435,18 @@ type UsageMetadata struct {
 	TotalTokenCount      int32 `json:"totalTokenCount"`
 }
 
+// GeminiErrorResponse represents an error in the Gemini API format
+type GeminiErrorResponse struct {
+	Error GeminiError `json:"error"`
+}
+
+// GeminiError carries the HTTP code and the canonical status name, e.g. RESOURCE_EXHAUSTED
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
 // ============= Request/Response Common Types =============
 
 // EmbeddingsRequest represents a request for embeddings
@@ -217,10 +457,10 @@ type EmbeddingsRequest struct {
 
 // EmbeddingsResponse represents embeddings response
 type EmbeddingsResponse struct {
-	Object string        `json:"object"`
-	Data   []Embedding   `json:"data"`
-	Model  string        `json:"model"`
-	Usage  Usage         `json:"usage"`
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  Usage       `json:"usage"`
 }
 
 // Embedding represents a single embedding
@@ -229,3 +469,59 @@ type Embedding struct {
 	Index     int       `json:"index"`
 	Embedding []float32 `json:"embedding"`
 }
+
+// ============= Admin Models =============
+
+// AccountStatusResponse lists the credential status of every Gemini account
+type AccountStatusResponse struct {
+	Accounts []AccountStatus `json:"accounts"`
+}
+
+// AccountStatus describes the cookies of one Gemini account
+type AccountStatus struct {
+	ID              int        `json:"id"`
+	Healthy         bool       `json:"healthy"`
+	Ejected         bool       `json:"ejected"`
+	InFlight        int64      `json:"in_flight"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	LastRotation    *time.Time `json:"last_rotation,omitempty"`
+	NextRotation    *time.Time `json:"next_rotation,omitempty"`
+	LastAuthError   string     `json:"last_auth_error,omitempty"`
+	LastAuthErrorAt *time.Time `json:"last_auth_error_at,omitempty"`
+}
+
+// UpdateCookiesRequest carries new cookies for an account, either as separate values
+// or in Cookies as a Cookie header, cookies.txt or exported cookie JSON
+type UpdateCookiesRequest struct {
+	Secure1PSID   string `json:"__Secure-1PSID,omitempty"`
+	Secure1PSIDTS string `json:"__Secure-1PSIDTS,omitempty"`
+	Secure1PSIDCC string `json:"__Secure-1PSIDCC,omitempty"`
+	Cookies       string `json:"cookies,omitempty"`
+}
+
+// ConversationListResponse lists the recent conversations of a Gemini account
+type ConversationListResponse struct {
+	Conversations []Conversation `json:"conversations"`
+}
+
+// Conversation is a chat in a Gemini account's history
+type Conversation struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// ConversationResponse holds the turns of a Gemini conversation, oldest first
+type ConversationResponse struct {
+	ID    string             `json:"id"`
+	Turns []ConversationTurn `json:"turns"`
+}
+
+// ConversationTurn is one prompt of a conversation and Gemini's answer to it
+type ConversationTurn struct {
+	ResponseID  string     `json:"response_id"`
+	CandidateID string     `json:"candidate_id,omitempty"`
+	Prompt      string     `json:"prompt"`
+	Response    string     `json:"response"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Sentinel errors shared by all providers. Providers wrap them with details
+// so callers can classify failures with errors.Is.
+var (
+	// ErrAuthExpired means the upstream rejected the provider's credentials
+	ErrAuthExpired = errors.New("authentication expired")
+
+	// ErrUnknownModel means the requested model is not served by the provider
+	ErrUnknownModel = errors.New("unknown model")
+
+	// ErrRateLimited means the upstream refused the request because of a usage limit
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrContentBlocked means the upstream refused to answer the prompt
+	ErrContentBlocked = errors.New("content blocked")
+
+	// ErrUpstreamUnavailable means the upstream could not be reached or failed on its side
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+
+	// ErrParseFailure means the upstream answered with something the provider could not parse
+	ErrParseFailure = errors.New("failed to parse upstream response")
+
+	// ErrTimeout means the upstream did not answer in time
+	ErrTimeout = errors.New("upstream timeout")
+)
+
+// Error classes that fallback rules refer to
+const (
+	ClassAuthExpired    = "auth_expired"
+	ClassUnknownModel   = "unknown_model"
+	ClassRateLimited    = "rate_limited"
+	ClassContentBlocked = "content_blocked"
+	ClassUnavailable    = "unavailable"
+	ClassParseFailure   = "parse_failure"
+	ClassTimeout        = "timeout"
+	ClassOther          = "other"
+)
+
+// errorClasses maps each sentinel error to its class
+var errorClasses = []struct {
+	target error
+	class  string
+}{
+	{ErrAuthExpired, ClassAuthExpired},
+	{ErrUnknownModel, ClassUnknownModel},
+	{ErrRateLimited, ClassRateLimited},
+	{ErrContentBlocked, ClassContentBlocked},
+	{ErrUpstreamUnavailable, ClassUnavailable},
+	{ErrParseFailure, ClassParseFailure},
+	{ErrTimeout, ClassTimeout},
+	{context.DeadlineExceeded, ClassTimeout},
+}
+
+// ErrorClass returns the class of a provider error, ClassOther for errors outside the taxonomy
+func ErrorClass(err error) string {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.target) {
+			return c.class
+		}
+	}
+	return ClassOther
+}
+
+// StatusError classifies a non-2xx response of an HTTP API. op names the failed request and
+// detail is the message of the error body, if any.
+func StatusError(op string, status int, detail string) error {
+	message := fmt.Sprintf("status %d", status)
+	if detail != "" {
+		message += ": " + detail
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %s failed with %s", ErrAuthExpired, op, message)
+	case status == http.StatusNotFound:
+		return fmt.Errorf("%w: %s failed with %s", ErrUnknownModel, op, message)
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s failed with %s", ErrRateLimited, op, message)
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		return fmt.Errorf("%w: %s failed with %s", ErrTimeout, op, message)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %s failed with %s", ErrUpstreamUnavailable, op, message)
+	default:
+		return fmt.Errorf("%s failed with %s", op, message)
+	}
+}
+
+// RequestError classifies a failure to get any response from upstream.
+// Cancellation by the caller is returned unchanged.
+func RequestError(op string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %s: %v", ErrTimeout, op, err)
+	}
+	return fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, op, err)
+}
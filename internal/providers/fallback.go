@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Attempt identifies the step of a route's fallback chain that served a request
+type Attempt struct {
+	Target
+	Fallback int // 0 for the route's own provider, n for its n-th fallback
+}
+
+// Generate runs a request through the fallback chain of a resolved route. Each target
+// is tried in turn while the failures match the route's fallback rules.
+func (pm *ProviderManager) Generate(ctx context.Context, route Route, prompt string, options ...GenerateOption) (*Response, Attempt, error) {
+	var lastErr error
+	for i, target := range route.chain() {
+		provider, err := pm.target(route, i, target)
+		if err == nil {
+			var response *Response
+			response, err = provider.GenerateContent(ctx, prompt, targetOptions(target, options)...)
+			if err == nil {
+				attempt := Attempt{Target: target, Fallback: i}
+				pm.logFallback(route, attempt)
+				return response, attempt, nil
+			}
+		}
+
+		lastErr = err
+		if !pm.fallBack(ctx, route, i, target, err) {
+			break
+		}
+	}
+	return nil, Attempt{}, lastErr
+}
+
+// GenerateStream runs a streamed request through the fallback chain of a resolved route.
+// A target is only left before it has streamed anything: the first chunk is awaited so
+// that an error it carries can still move the request on.
+func (pm *ProviderManager) GenerateStream(ctx context.Context, route Route, prompt string, options ...GenerateOption) (<-chan StreamChunk, Attempt, error) {
+	var lastErr error
+	for i, target := range route.chain() {
+		provider, err := pm.target(route, i, target)
+		if err == nil {
+			var stream <-chan StreamChunk
+			stream, err = provider.GenerateContentStream(ctx, prompt, targetOptions(target, options)...)
+			if err == nil {
+				if stream, err = peek(ctx, stream); err == nil {
+					attempt := Attempt{Target: target, Fallback: i}
+					pm.logFallback(route, attempt)
+					return stream, attempt, nil
+				}
+			}
+		}
+
+		lastErr = err
+		if !pm.fallBack(ctx, route, i, target, err) {
+			break
+		}
+	}
+	return nil, Attempt{}, lastErr
+}
+
+// target returns the provider of a step of the chain. Unhealthy providers are skipped
+// when the route falls back on unavailable upstreams and another step is left.
+func (pm *ProviderManager) target(route Route, step int, target Target) (Provider, error) {
+	provider := pm.factory.Get(target.Provider)
+	if provider == nil {
+		return nil, fmt.Errorf("%w: provider %q is not available", ErrUpstreamUnavailable, target.Provider)
+	}
+	if !provider.IsHealthy() && step < len(route.Fallbacks) && route.fallsBackOn(ErrUpstreamUnavailable) {
+		return nil, fmt.Errorf("%w: provider %q is unhealthy", ErrUpstreamUnavailable, target.Provider)
+	}
+	return provider, nil
+}
+
+// fallBack reports whether a failed step is followed by another one, logging the switch
+func (pm *ProviderManager) fallBack(ctx context.Context, route Route, step int, target Target, err error) bool {
+	if step >= len(route.Fallbacks) || ctx.Err() != nil || !route.fallsBackOn(err) {
+		return false
+	}
+	next := route.Fallbacks[step]
+	pm.log.Warn("Falling back to the next provider of the route",
+		zap.String("model", route.ID),
+		zap.String("failed_provider", target.Provider),
+		zap.Int("failed_account", target.Account),
+		zap.String("next_provider", next.Provider),
+		zap.Int("next_account", next.Account),
+		zap.String("error_class", ErrorClass(err)),
+		zap.Error(err))
+	return true
+}
+
+// logFallback records which fallback served a request
+func (pm *ProviderManager) logFallback(route Route, attempt Attempt) {
+	if attempt.Fallback == 0 {
+		return
+	}
+	pm.log.Info("Request served by a fallback",
+		zap.String("model", route.ID),
+		zap.Int("fallback", attempt.Fallback),
+		zap.String("provider", attempt.Provider),
+		zap.String("upstream_model", attempt.Model),
+		zap.Int("account", attempt.Account))
+}
+
+// targetOptions adds the model and account of a target to the request options
+func targetOptions(target Target, options []GenerateOption) []GenerateOption {
+	opts := append([]GenerateOption{}, options...)
+	opts = append(opts, WithModel(target.Model))
+	if target.Account != 0 {
+		opts = append(opts, WithAccount(target.Account))
+	}
+	return opts
+}
+
+// peek waits for the first chunk of a stream and returns its error, if it carries one.
+// Otherwise the returned stream replays the chunk before the rest.
+func peek(ctx context.Context, stream <-chan StreamChunk) (<-chan StreamChunk, error) {
+	var first StreamChunk
+	var ok bool
+	select {
+	case first, ok = <-stream:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: stream ended without a response", ErrParseFailure)
+	}
+	if first.Err != nil {
+		return nil, first.Err
+	}
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk, ok := first, true; ok; chunk, ok = <-stream {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,314 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeProvider answers every request with a fixed text or error and records the requests
+type fakeProvider struct {
+	name      string
+	unhealthy bool
+	err       error // returned instead of an answer
+	streamErr error // sent after the first chunk of a stream
+	models    []ModelInfo
+
+	mu    sync.Mutex
+	calls []GenerateConfig
+}
+
+func (f *fakeProvider) Init(ctx context.Context) error { return nil }
+func (f *fakeProvider) Close() error                   { return nil }
+func (f *fakeProvider) GetName() string                { return f.name }
+func (f *fakeProvider) IsHealthy() bool                { return !f.unhealthy }
+func (f *fakeProvider) ListModels() []ModelInfo        { return f.models }
+func (f *fakeProvider) StartChat(options ...ChatOption) ChatSession {
+	return nil
+}
+
+func (f *fakeProvider) record(options []GenerateOption) GenerateConfig {
+	var config GenerateConfig
+	for _, opt := range options {
+		opt(&config)
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, config)
+	f.mu.Unlock()
+	return config
+}
+
+func (f *fakeProvider) answer(config GenerateConfig) string {
+	return fmt.Sprintf("%s answers with %s", f.name, config.Model)
+}
+
+func (f *fakeProvider) GenerateContent(ctx context.Context, prompt string, options ...GenerateOption) (*Response, error) {
+	config := f.record(options)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Response{Text: f.answer(config)}, nil
+}
+
+func (f *fakeProvider) GenerateContentStream(ctx context.Context, prompt string, options ...GenerateOption) (<-chan StreamChunk, error) {
+	config := f.record(options)
+	chunks := make(chan StreamChunk, 3)
+	defer close(chunks)
+	if f.err != nil {
+		chunks <- StreamChunk{Err: f.err}
+		return chunks, nil
+	}
+	text := f.answer(config)
+	chunks <- StreamChunk{Text: text}
+	if f.streamErr != nil {
+		chunks <- StreamChunk{Err: f.streamErr}
+		return chunks, nil
+	}
+	chunks <- StreamChunk{Response: &Response{Text: text}}
+	return chunks, nil
+}
+
+// newTestManager returns a manager over routes with the given providers registered by name
+func newTestManager(routes []Route, providers ...*fakeProvider) *ProviderManager {
+	pm := &ProviderManager{factory: NewFactory(), routes: routes, log: zap.NewNop()}
+	for _, p := range providers {
+		pm.Register(p.name, p)
+	}
+	return pm
+}
+
+// calledProviders lists the providers that got a request, in call order of the chain
+func calledProviders(providers ...*fakeProvider) string {
+	var called []string
+	for _, p := range providers {
+		for range p.calls {
+			called = append(called, p.name)
+		}
+	}
+	return strings.Join(called, ",")
+}
+
+func TestFallbackChain(t *testing.T) {
+	// step sets up how one provider of the chain behaves
+	type step struct {
+		err       error
+		unhealthy bool
+	}
+	tests := []struct {
+		name       string
+		fallbackOn []string
+		a, b, c    step
+		wantCalls  string
+		wantStep   int
+		wantErr    error
+	}{
+		{
+			name:      "served by the route's provider",
+			wantCalls: "a",
+			wantStep:  0,
+		},
+		{
+			name:      "rate limit moves on in chain order",
+			a:         step{err: ErrRateLimited},
+			b:         step{err: ErrUpstreamUnavailable},
+			wantCalls: "a,b,c",
+			wantStep:  2,
+		},
+		{
+			name:      "timeout moves on",
+			a:         step{err: ErrTimeout},
+			wantCalls: "a,b",
+			wantStep:  1,
+		},
+		{
+			name:      "content blocks are never retried elsewhere",
+			a:         step{err: ErrContentBlocked},
+			wantCalls: "a",
+			wantErr:   ErrContentBlocked,
+		},
+		{
+			name:      "auth errors are not in the default rules",
+			a:         step{err: ErrAuthExpired},
+			wantCalls: "a",
+			wantErr:   ErrAuthExpired,
+		},
+		{
+			name:       "configured classes replace the default rules",
+			fallbackOn: []string{ClassAuthExpired},
+			a:          step{err: ErrAuthExpired},
+			wantCalls:  "a,b",
+			wantStep:   1,
+		},
+		{
+			name:       "classes outside the configured rules are returned",
+			fallbackOn: []string{ClassAuthExpired},
+			a:          step{err: ErrRateLimited},
+			wantCalls:  "a",
+			wantErr:    ErrRateLimited,
+		},
+		{
+			name:      "the last error is returned when every step fails",
+			a:         step{err: ErrRateLimited},
+			b:         step{err: ErrRateLimited},
+			c:         step{err: ErrTimeout},
+			wantCalls: "a,b,c",
+			wantErr:   ErrTimeout,
+		},
+		{
+			name:      "unhealthy providers are skipped",
+			a:         step{unhealthy: true},
+			wantCalls: "b",
+			wantStep:  1,
+		},
+		{
+			name:       "unhealthy providers are tried when the route does not fall back on unavailable upstreams",
+			fallbackOn: []string{ClassRateLimited},
+			a:          step{unhealthy: true},
+			wantCalls:  "a",
+			wantStep:   0,
+		},
+		{
+			name:      "the last step is tried even when unhealthy",
+			a:         step{err: ErrRateLimited},
+			b:         step{err: ErrRateLimited},
+			c:         step{unhealthy: true},
+			wantCalls: "a,b,c",
+			wantStep:  2,
+		},
+	}
+
+	for _, stream := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.name, stream), func(t *testing.T) {
+				a := &fakeProvider{name: "a", err: tt.a.err, unhealthy: tt.a.unhealthy}
+				b := &fakeProvider{name: "b", err: tt.b.err, unhealthy: tt.b.unhealthy}
+				c := &fakeProvider{name: "c", err: tt.c.err, unhealthy: tt.c.unhealthy}
+				pm := newTestManager([]Route{{
+					ID:         "model",
+					Provider:   "a",
+					Model:      "upstream",
+					Fallbacks:  []Target{{Provider: "b"}, {Provider: "c", Model: "other", Account: 2}},
+					FallbackOn: tt.fallbackOn,
+				}}, a, b, c)
+
+				route, err := pm.Resolve("model")
+				if err != nil {
+					t.Fatalf("Resolve: %v", err)
+				}
+				var text string
+				var attempt Attempt
+				if stream {
+					var chunks <-chan StreamChunk
+					chunks, attempt, err = pm.GenerateStream(context.Background(), route, "hello")
+					if err == nil {
+						var response *Response
+						response, err = CollectStream(chunks)
+						if err == nil {
+							text = response.Text
+						}
+					}
+				} else {
+					var response *Response
+					response, attempt, err = pm.Generate(context.Background(), route, "hello")
+					if err == nil {
+						text = response.Text
+					}
+				}
+
+				if got := calledProviders(a, b, c); got != tt.wantCalls {
+					t.Errorf("called %q, want %q", got, tt.wantCalls)
+				}
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("error %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				want := []Attempt{
+					{Target: Target{Provider: "a", Model: "upstream"}, Fallback: 0},
+					{Target: Target{Provider: "b", Model: "upstream"}, Fallback: 1},
+					{Target: Target{Provider: "c", Model: "other", Account: 2}, Fallback: 2},
+				}[tt.wantStep]
+				if attempt != want {
+					t.Errorf("attempt %+v, want %+v", attempt, want)
+				}
+				if wantText := fmt.Sprintf("%s answers with %s", want.Provider, want.Model); text != wantText {
+					t.Errorf("answer %q, want %q", text, wantText)
+				}
+			})
+		}
+	}
+}
+
+func TestFallbackTargetsGetTheirAccount(t *testing.T) {
+	a := &fakeProvider{name: "a", err: ErrRateLimited}
+	pool := &fakeProvider{name: "pool"}
+	pm := newTestManager([]Route{{
+		ID:        "model",
+		Provider:  "a",
+		Fallbacks: []Target{{Provider: "pool", Account: 3}},
+	}}, a, pool)
+
+	route, _ := pm.Resolve("model")
+	if _, _, err := pm.Generate(context.Background(), route, "hello", WithModel("ignored")); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(pool.calls) != 1 || pool.calls[0].Account != 3 || pool.calls[0].Model != "model" {
+		t.Errorf("fallback request %+v, want account 3 and the route's model", pool.calls)
+	}
+}
+
+func TestNoFallbackAfterFirstChunk(t *testing.T) {
+	a := &fakeProvider{name: "a", streamErr: ErrUpstreamUnavailable}
+	b := &fakeProvider{name: "b"}
+	pm := newTestManager([]Route{{ID: "model", Provider: "a", Fallbacks: []Target{{Provider: "b"}}}}, a, b)
+
+	route, _ := pm.Resolve("model")
+	chunks, attempt, err := pm.GenerateStream(context.Background(), route, "hello")
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if attempt.Provider != "a" {
+		t.Errorf("stream served by %q, want a", attempt.Provider)
+	}
+
+	var streamed strings.Builder
+	var streamErr error
+	for chunk := range chunks {
+		streamed.WriteString(chunk.Text)
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+		}
+	}
+	// The client already has the first provider's text, so its failure ends the stream
+	if !errors.Is(streamErr, ErrUpstreamUnavailable) {
+		t.Errorf("stream error %v, want ErrUpstreamUnavailable", streamErr)
+	}
+	if streamed.String() != "a answers with model" {
+		t.Errorf("streamed %q", streamed.String())
+	}
+	if len(b.calls) != 0 {
+		t.Errorf("fallback called after the stream started")
+	}
+}
+
+func TestFallbackRulesRejectContentBlocks(t *testing.T) {
+	err := validateRoutes([]Route{{
+		ID:         "model",
+		Provider:   "a",
+		Fallbacks:  []Target{{Provider: "b"}},
+		FallbackOn: []string{ClassContentBlocked},
+	}})
+	if err == nil || !strings.Contains(err.Error(), ClassContentBlocked) {
+		t.Errorf("error %v, want content_blocked rejected", err)
+	}
+}
@@ -0,0 +1,118 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// RPC IDs of the batchexecute calls the web app makes
+const (
+	rpcListGems           = "CNgdBe"
+	rpcListConversations  = "MaZiqc"
+	rpcReadConversation   = "hNvQHb"
+	rpcDeleteConversation = "GzXR5e"
+)
+
+// rpcCall is one call of a batchexecute request
+type rpcCall struct {
+	ID         string // RPC ID
+	Payload    string // JSON-encoded arguments
+	Identifier string // tells apart several calls to the same RPC, "generic" when empty
+}
+
+// batchExecute sends calls in a single batchexecute request and returns the JSON answer of
+// each call keyed by its identifier. Calls that upstream did not answer are missing from the map.
+func (c *Client) batchExecute(ctx context.Context, calls []rpcCall) (map[string]string, error) {
+	return reauthRetry(ctx, c, func() (map[string]string, error) {
+		return c.doBatchExecute(ctx, calls)
+	})
+}
+
+func (c *Client) doBatchExecute(ctx context.Context, calls []rpcCall) (map[string]string, error) {
+	c.mu.RLock()
+	at := c.at
+	c.mu.RUnlock()
+	if at == "" {
+		return nil, fmt.Errorf("%w: client not initialized", providers.ErrAuthExpired)
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	req := make([]interface{}, 0, len(calls))
+	for _, call := range calls {
+		identifier := call.Identifier
+		if identifier == "" {
+			identifier = "generic"
+		}
+		req = append(req, []interface{}{call.ID, call.Payload, nil, identifier})
+		if !seen[call.ID] {
+			seen[call.ID] = true
+			ids = append(ids, call.ID)
+		}
+	}
+	reqJSON, _ := json.Marshal([]interface{}{req})
+	op := "batchexecute " + strings.Join(ids, ",")
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetHeaders(DefaultHeaders).
+		SetQueryParams(map[string]string{
+			"rpcids":      strings.Join(ids, ","),
+			"source-path": "/app",
+			"rt":          "c",
+		}).
+		SetFormData(map[string]string{
+			"at":    at,
+			"f.req": string(reqJSON),
+		}).
+		Post(EndpointBatchExec)
+	if err != nil {
+		return nil, providers.RequestError(op, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(op, resp.StatusCode)
+	}
+
+	answers := parseBatchResponse(resp.String())
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("%w: %s returned no answers", providers.ErrParseFailure, op)
+	}
+	return answers, nil
+}
+
+// parseBatchResponse collects the "wrb.fr" answers of a batchexecute response, keyed by
+// the identifier of the call they answer
+func parseBatchResponse(body string) map[string]string {
+	answers := make(map[string]string)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), ")]}'")
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		var root []interface{}
+		if err := json.Unmarshal([]byte(line), &root); err != nil {
+			continue
+		}
+		for _, item := range root {
+			if kind, _ := dig(item, 0).(string); kind != "wrb.fr" {
+				continue
+			}
+			// Calls without a result, like deletions, answer with a null payload
+			payload, _ := dig(item, 2).(string)
+			identifier := "generic"
+			if fields, _ := item.([]interface{}); len(fields) > 0 {
+				if id, ok := fields[len(fields)-1].(string); ok {
+					identifier = id
+				}
+			}
+			answers[identifier] = payload
+		}
+	}
+	return answers
+}
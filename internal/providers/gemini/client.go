@@ -1,17 +1,11 @@
 package gemini
 
 import (
-	"compress/gzip"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
@@ -26,17 +20,52 @@ import (
 
 type Client struct {
 	httpClient *req.Client
+	jar        *cookieJar
+	account    config.GeminiAccount
+	cfg        *config.Config
+	rawCookies string // GEMINI_COOKIES as configured, parsed at Init
+	uploadURL  string
+	cacheDir   string
+	store      CredentialStore
 	cookies    *CookieStore
-	at         string 
+	at         string
 	mu         sync.RWMutex
 	healthy    bool
 	log        *zap.Logger
-	
+
 	autoRefresh     bool
 	refreshInterval time.Duration
 	stopRefresh     chan struct{}
+	refreshOnce     sync.Once
+	closeOnce       sync.Once
 
 	reqMu sync.Mutex
+
+	// rotateMu serializes cookie rotations; the cookie values themselves are guarded by cookies.mu
+	rotateMu sync.Mutex
+
+	// authMu serializes re-authentication and guards the last attempt;
+	// authGen counts fetched session tokens
+	authMu          sync.Mutex
+	authGen         uint64
+	lastAuthAttempt time.Time
+	lastAuthErr     error
+
+	// Credential status reported by Status, guarded by mu
+	lastRotation  time.Time
+	nextRotation  time.Time
+	authError     error
+	authErrorTime time.Time
+
+	// Gems of the account, cached by cachedGems
+	gemsMu      sync.Mutex
+	gems        []Gem
+	gemsFetched time.Time
+
+	// Conversations used by the bridge and when, deleted once idle for conversationTTL
+	conversationTTL time.Duration
+	convMu          sync.Mutex
+	conversations   map[string]time.Time
 }
 
 type CookieStore struct {
@@ -49,60 +78,136 @@ type CookieStore struct {
 
 const (
 	defaultRefreshIntervalMinutes = 30
+
+	// defaultLocale is used when neither the request nor the account sets a locale
+	defaultLocale = "en-US"
 )
 
-func NewClient(cfg *config.Config, log *zap.Logger) *Client {
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithUploadURL sends file uploads to url instead of Gemini's content push service
+func WithUploadURL(url string) ClientOption {
+	return func(c *Client) {
+		c.uploadURL = url
+	}
+}
+
+// NewClient creates a client for a single Google account
+func NewClient(account config.GeminiAccount, cfg *config.Config, store CredentialStore, log *zap.Logger, options ...ClientOption) *Client {
 	cookies := &CookieStore{
-		Secure1PSID:   cfg.Gemini.Secure1PSID,
-		Secure1PSIDTS: cfg.Gemini.Secure1PSIDTS,
-		Secure1PSIDCC: cfg.Gemini.Secure1PSIDCC,
+		Secure1PSID:   account.Secure1PSID,
+		Secure1PSIDTS: account.Secure1PSIDTS,
+		Secure1PSIDCC: account.Secure1PSIDCC,
 		UpdatedAt:     time.Now(),
 	}
 
-	client := req.NewClient().
-		SetTimeout(2 * time.Minute).
-		SetCommonHeaders(DefaultHeaders)
+	// The proxy URL was validated with the rest of the configuration
+	var proxy *url.URL
+	if account.Proxy != "" {
+		proxy, _ = url.Parse(account.Proxy)
+	}
+
+	if proxy != nil {
+		log.Info("Routing Gemini traffic through proxy", zap.String("proxy", proxy.Redacted()))
+	}
+
+	profile := resolveBrowserProfile(account)
+	jar := newCookieJar()
+	client := newTransport(profile, proxy, jar, account.Locale)
+	log.Debug("Using browser profile", zap.String("profile", profile.Name), zap.String("user_agent", profile.UserAgent))
 
 	refreshIntervalMinutes := cfg.Gemini.RefreshInterval
 	if refreshIntervalMinutes <= 0 {
 		refreshIntervalMinutes = defaultRefreshIntervalMinutes
 	}
 
-	return &Client{
+	c := &Client{
 		httpClient:      client,
+		jar:             jar,
+		account:         account,
+		cfg:             cfg,
+		rawCookies:      account.Cookies,
+		uploadURL:       EndpointUpload,
+		cacheDir:        cfg.Gemini.CookieCacheDir,
+		store:           store,
 		cookies:         cookies,
 		autoRefresh:     true,
 		refreshInterval: time.Duration(refreshIntervalMinutes) * time.Minute,
 		stopRefresh:     make(chan struct{}),
 		log:             log,
+		conversationTTL: time.Duration(cfg.Gemini.ConversationTTL) * time.Minute,
+		conversations:   make(map[string]time.Time),
+	}
+	for _, opt := range options {
+		opt(c)
 	}
+	return c
 }
 
 func (c *Client) Init(ctx context.Context) error {
+	// Import cookies pasted as a whole; separately configured values take precedence
+	var imported []*http.Cookie
+	if c.rawCookies != "" {
+		var err error
+		if imported, err = ParseCookies(c.rawCookies); err != nil {
+			return fmt.Errorf("invalid GEMINI_COOKIES: %w", err)
+		}
+		c.cookies.fill(imported)
+		if c.GetCookies().Secure1PSID == "" {
+			return errors.New("invalid GEMINI_COOKIES: no __Secure-1PSID cookie found")
+		}
+		c.log.Info("Imported cookies", zap.Int("cookies", len(imported)))
+	}
+
 	// Clean cookies
+	c.cookies.mu.Lock()
 	c.cookies.Secure1PSID = cleanCookie(c.cookies.Secure1PSID)
 	configPSIDTS := cleanCookie(c.cookies.Secure1PSIDTS) // Save original config value
 	c.cookies.Secure1PSIDTS = configPSIDTS
 	c.cookies.Secure1PSIDCC = cleanCookie(c.cookies.Secure1PSIDCC)
+	psid := c.cookies.Secure1PSID
+	c.cookies.mu.Unlock()
 
 	// Check if we should use cached cookies or clear cache
-	if c.cookies.Secure1PSID != "" {
-		cachedTS, err := c.LoadCachedCookies()
-		
+	restored := false
+	if psid != "" {
+		cache, err := c.LoadCachedCookies()
+		cachedTS := ""
+		if err == nil {
+			cachedTS = cache.value("__Secure-1PSIDTS")
+		}
+
 		// If config has a new PSIDTS that differs from cache, clear cache and use config
 		if configPSIDTS != "" && cachedTS != "" && configPSIDTS != cachedTS {
 			c.log.Info("Config has new __Secure-1PSIDTS, clearing old cache")
 			_ = c.ClearCookieCache()
 			// Keep using the config value (already set above)
-		} else if err == nil && cachedTS != "" && configPSIDTS == "" {
-			// Only use cache if config doesn't provide PSIDTS
+		} else if cachedTS != "" {
+			// Restore the whole jar so the session picks up where the last run left off
+			c.jar.restore(cache.Cookies)
+			c.cookies.mu.Lock()
 			c.cookies.Secure1PSIDTS = cachedTS
-			c.log.Info("Loaded __Secure-1PSIDTS from cache")
+			if cc := cache.value("__Secure-1PSIDCC"); cc != "" {
+				c.cookies.Secure1PSIDCC = cc
+			}
+			if !cache.UpdatedAt.IsZero() {
+				c.cookies.UpdatedAt = cache.UpdatedAt
+			}
+			c.cookies.mu.Unlock()
+			restored = true
+			c.log.Info("Restored cookies from cache", zap.Int("cookies", len(cache.Cookies)))
 		}
 	}
 
+	// Populate cookies, unless the cache already filled the jar
+	if !restored {
+		c.jar.add(imported)
+		c.jar.SetCookies(googleURL, c.cookies.ToHTTPCookies())
+	}
+
 	// Obtain PSIDTS via rotation if missing
-	if c.cookies.Secure1PSID != "" && c.cookies.Secure1PSIDTS == "" {
+	if cookies := c.GetCookies(); cookies.Secure1PSID != "" && cookies.Secure1PSIDTS == "" {
 		c.log.Info("Only __Secure-1PSID provided, attempting to obtain __Secure-1PSIDTS via rotation...")
 		if err := c.RotateCookies(); err != nil {
 			c.log.Info("Rotation failed, proceeding with just __Secure-1PSID (might fail)", zap.String("error", err.Error()))
@@ -111,23 +216,7 @@ func (c *Client) Init(ctx context.Context) error {
 		}
 	}
 
-	// Populate cookies
-	c.httpClient.SetCommonCookies(c.cookies.ToHTTPCookies()...)
-
-	// Get SNlM0e token
-	err := c.refreshSessionToken()
-	if err != nil {
-		c.log.Debug("Initial session token fetch failed, attempting cookie rotation", zap.Error(err))
-		// Try to rotate cookies and retry
-		if rotErr := c.RotateCookies(); rotErr == nil {
-			c.log.Debug("Cookie rotation succeeded, retrying session token fetch")
-			err = c.refreshSessionToken()
-		} else {
-			c.log.Debug("Cookie rotation failed", zap.Error(rotErr))
-		}
-	}
-
-	if err != nil {
+	if err := c.authenticate(); err != nil {
 		return err
 	}
 
@@ -137,133 +226,64 @@ func (c *Client) Init(ctx context.Context) error {
 	c.log.Info("✅ Gemini client initialized successfully")
 
 	// 5. Start auto-refresh in background
-	if c.autoRefresh {
-		go c.startAutoRefresh()
-	}
+	c.ensureAutoRefresh()
 
 	return nil
 }
 
-func (c *Client) refreshSessionToken() error {
-	// 1. Initial hit to google.com to get extra cookies (NID, etc)
-	tmpClient := req.NewClient().
-		SetTimeout(30 * time.Second).
-		SetUserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	
-	resp1, err := tmpClient.R().Get("https://www.google.com/")
-	extraCookies := ""
-	if err == nil {
-		parts := []string{}
-		for _, ck := range resp1.Cookies() {
-			parts = append(parts, fmt.Sprintf("%s=%s", ck.Name, ck.Value))
-			// Also sync to main client
-			c.httpClient.SetCommonCookies(ck)
-		}
-		if len(parts) > 0 {
-			extraCookies = strings.Join(parts, "; ") + "; "
-		}
+// ensureAutoRefresh starts the background cookie rotation once per client
+func (c *Client) ensureAutoRefresh() {
+	if !c.autoRefresh {
+		return
 	}
+	c.refreshOnce.Do(func() {
+		go c.startAutoRefresh()
+	})
+}
 
-	// 2. Prepare full cookie string
-	cookieStr := fmt.Sprintf("%s__Secure-1PSID=%s; __Secure-1PSIDTS=%s", 
-		extraCookies, c.cookies.Secure1PSID, c.cookies.Secure1PSIDTS)
-	if c.cookies.Secure1PSIDCC != "" {
-		cookieStr += fmt.Sprintf("; __Secure-1PSIDCC=%s", c.cookies.Secure1PSIDCC)
-	}
-
-	commonHeaders := map[string]string{
-		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
-		"Accept-Language":           "en-US,en;q=0.9",
-		"Cache-Control":             "max-age=0",
-		"Origin":                    "https://gemini.google.com",
-		"Sec-Ch-Ua":                 `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
-		"Sec-Ch-Ua-Mobile":          "?0",
-		"Sec-Ch-Ua-Platform":        `"Windows"`,
-		"Sec-Fetch-Dest":            "document",
-		"Sec-Fetch-Mode":            "navigate",
-		"Sec-Fetch-Site":            "none",
-		"Sec-Fetch-User":            "?1",
-		"Upgrade-Insecure-Requests": "1",
-		"X-Same-Domain":             "1",
-		"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-	}
-
-	hClient := &http.Client{
-		Timeout: 30 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return nil // follow redirects
-		},
-	}
-
-	// Helper to merge cookies into a map to avoid duplicates
-	mergeCookies := func(baseStr string, newCks []*http.Cookie) string {
-		m := make(map[string]string)
-		for _, part := range strings.Split(baseStr, ";") {
-			p := strings.TrimSpace(part)
-			if p == "" {
-				continue
-			}
-			kv := strings.SplitN(p, "=", 2)
-			if len(kv) == 2 {
-				m[kv[0]] = kv[1]
-			}
-		}
-		for _, ck := range newCks {
-			m[ck.Name] = ck.Value
-		}
-		res := []string{}
-		for k, v := range m {
-			res = append(res, fmt.Sprintf("%s=%s", k, v))
+// authenticate fetches the SNlM0e token, rotating cookies once if the first attempt fails
+func (c *Client) authenticate() error {
+	err := c.refreshSessionToken()
+	if err != nil {
+		c.log.Debug("Initial session token fetch failed, attempting cookie rotation", zap.Error(err))
+		// Try to rotate cookies and retry
+		if rotErr := c.RotateCookies(); rotErr == nil {
+			c.log.Debug("Cookie rotation succeeded, retrying session token fetch")
+			err = c.refreshSessionToken()
+		} else {
+			c.log.Debug("Cookie rotation failed", zap.Error(rotErr))
 		}
-		return strings.Join(res, "; ")
 	}
-
-	req1, _ := http.NewRequest("GET", "https://gemini.google.com/?hl=en", nil)
-	for k, v := range commonHeaders {
-		req1.Header.Set(k, v)
-	}
-	req1.Header.Set("Cookie", cookieStr)
-	resp1_direct, _ := hClient.Do(req1)
-	if resp1_direct != nil {
-		cookieStr = mergeCookies(cookieStr, resp1_direct.Cookies())
-		for _, ck := range resp1_direct.Cookies() {
-			c.httpClient.SetCommonCookies(ck)
-		}
-		resp1_direct.Body.Close()
+	if err != nil {
+		c.recordAuthError(err)
 	}
+	return err
+}
 
-	// 2. The main INIT hit
-	req2, _ := http.NewRequest("GET", EndpointInit+"?hl=en", nil)
-	for k, v := range commonHeaders {
-		req2.Header.Set(k, v)
-	}
-	req2.Header.Set("Sec-Fetch-Site", "same-origin")
-	req2.Header.Set("Cookie", cookieStr)
-	req2.Header.Set("Referer", "https://gemini.google.com/")
-	req2.Header.Set("Accept-Encoding", "gzip, deflate, br")
+func (c *Client) refreshSessionToken() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	resp, err := hClient.Do(req2)
-	if err != nil {
-		return fmt.Errorf("failed to reach gemini app: %w", err)
+	// 1. Visit google.com and the Gemini landing page first, like a browser would,
+	// so the jar picks up NID and the other cookies the app expects. A jar restored
+	// from the cache already has them.
+	if !c.jar.has(googleURL, "NID") {
+		_, _ = c.httpClient.R().SetContext(ctx).SetHeaders(NavigationHeaders).Get(EndpointGoogle)
+		_, _ = c.httpClient.R().SetContext(ctx).SetHeaders(NavigationHeaders).Get("https://gemini.google.com/?hl=" + url.QueryEscape(c.locale("")))
 	}
-	defer resp.Body.Close()
 
-	// Dump for debugging if it fails
-	// reqDump, _ := httputil.DumpRequestOut(req2, false)
-	// respDump, _ := httputil.DumpResponse(resp, false)
-	
-	var bodyReader io.ReadCloser = resp.Body
-	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
-		gz, err := gzip.NewReader(resp.Body)
-		if err == nil {
-			bodyReader = gz
-			defer gz.Close()
-		}
+	// 2. The main INIT hit
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetHeaders(NavigationHeaders).
+		SetHeader("Sec-Fetch-Site", "same-origin").
+		SetHeader("Referer", "https://gemini.google.com/").
+		SetQueryParam("hl", c.locale("")).
+		Get(EndpointInit)
+	if err != nil {
+		return providers.RequestError("reach gemini app", err)
 	}
-
-	bodyBytes, _ := io.ReadAll(bodyReader)
-	body := string(bodyBytes)
-
+	body := resp.String()
 
 	re := regexp.MustCompile(`"SNlM0e":"([^"]+)"`)
 	matches := re.FindStringSubmatch(body)
@@ -271,8 +291,6 @@ func (c *Client) refreshSessionToken() error {
 		reFallback := regexp.MustCompile(`\["SNlM0e","([^"]+)"\]`)
 		matches = reFallback.FindStringSubmatch(body)
 		if len(matches) < 2 {
-
-
 			errMsg := "authentication failed: SNlM0e not found"
 			if strings.Contains(body, "Sign in") || strings.Contains(body, "login") {
 				errMsg = "authentication failed: cookies invalid. Please provide __Secure-1PSIDTS in addition to __Secure-1PSID"
@@ -280,12 +298,13 @@ func (c *Client) refreshSessionToken() error {
 
 			// Log as Info to avoid stack trace for expected auth failures
 			c.log.Info(errMsg)
-			return fmt.Errorf("%s", errMsg)
+			return fmt.Errorf("%w: %s", providers.ErrAuthExpired, errMsg)
 		}
 	}
 
 	c.mu.Lock()
 	c.at = matches[1]
+	c.authGen++
 	c.healthy = true
 	c.mu.Unlock()
 	return nil
@@ -295,10 +314,12 @@ func (c *Client) refreshSessionToken() error {
 func (c *Client) startAutoRefresh() {
 	ticker := time.NewTicker(c.refreshInterval)
 	defer ticker.Stop()
+	c.scheduleRotation()
 
 	for {
 		select {
 		case <-ticker.C:
+			c.scheduleRotation()
 			if err := c.RotateCookies(); err != nil {
 				c.log.Error("Cookie rotation failed", zap.Error(err))
 			}
@@ -309,133 +330,130 @@ func (c *Client) startAutoRefresh() {
 }
 
 func (c *Client) RotateCookies() error {
-	c.cookies.mu.Lock()
-	defer c.cookies.mu.Unlock()
-
-	// Prepare cookies for rotation request
-	// NOTE: We access fields directly instead of using ToHTTPCookies() to avoid recursive locking (deadlock)
-	parts := []string{}
-	if c.cookies.Secure1PSID != "" {
-		parts = append(parts, fmt.Sprintf("__Secure-1PSID=%s", c.cookies.Secure1PSID))
-	}
-	if c.cookies.Secure1PSIDTS != "" {
-		parts = append(parts, fmt.Sprintf("__Secure-1PSIDTS=%s", c.cookies.Secure1PSIDTS))
-	}
-	if c.cookies.Secure1PSIDCC != "" {
-		parts = append(parts, fmt.Sprintf("__Secure-1PSIDCC=%s", c.cookies.Secure1PSIDCC))
-	}
-	cookieStr := strings.Join(parts, "; ")
+	c.rotateMu.Lock()
+	defer c.rotateMu.Unlock()
 
-	// Payload must be exactly this string
-	strBody := `[000,"-0000000000000000000"]`
-	req, _ := http.NewRequest("POST", EndpointRotateCookies, strings.NewReader(strBody))
-	
-	req.Header.Set("Content-Type", "application/json")
-	// Google often blocks requests with default Go-http-client User-Agent
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Cookie", cookieStr)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
+	// The account cookies come from the shared jar. Payload must be exactly this string.
 	c.log.Debug("Sending rotation request", zap.String("url", EndpointRotateCookies))
-	hClient := &http.Client{Timeout: 5 * time.Second}
-	resp, err := hClient.Do(req)
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBodyString(`[000,"-0000000000000000000"]`).
+		Post(EndpointRotateCookies)
 	if err != nil {
 		// Log as Info to avoid scary stacktraces in development mode for expected auth failures
 		c.log.Info("Rotation request failed (network/auth issue)", zap.String("error", err.Error()))
-		return fmt.Errorf("failed to call rotation endpoint: %w", err)
+		err = fmt.Errorf("failed to call rotation endpoint: %w", err)
+		c.recordAuthError(err)
+		return err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		c.log.Info("Rotation failed (likely invalid __Secure-1PSID)", zap.Int("status", resp.StatusCode))
-		return fmt.Errorf("rotation failed with status %d", resp.StatusCode)
+		err := fmt.Errorf("rotation failed with status %d", resp.StatusCode)
+		c.recordAuthError(err)
+		return err
 	}
 
 	// Extract new PSIDTS from Set-Cookie headers
 	found := false
+	now := time.Now()
+	c.cookies.mu.Lock()
 	for _, cookie := range resp.Cookies() {
 		if cookie.Name == "__Secure-1PSIDTS" {
 			c.cookies.Secure1PSIDTS = cookie.Value
-			c.cookies.UpdatedAt = time.Now()
+			c.cookies.UpdatedAt = now
 			found = true
-			// Save the new cookie to cache immediately
-			_ = c.SaveCachedCookies()
 		}
 		if cookie.Name == "__Secure-1PSIDCC" {
 			c.cookies.Secure1PSIDCC = cookie.Value
 		}
-		// Sync to req/v3 client for future calls
-		c.httpClient.SetCommonCookies(cookie)
 	}
+	c.cookies.mu.Unlock()
 
 	if found {
-		c.log.Info("Cookie rotated successfully", zap.Time("updated_at", c.cookies.UpdatedAt))
+		// Save the new cookie to cache immediately
+		_ = c.SaveCachedCookies()
+		c.mu.Lock()
+		c.lastRotation = now
+		c.mu.Unlock()
+		c.log.Info("Cookie rotated successfully", zap.Time("updated_at", now))
 		return nil
 	}
 
-	return errors.New("no new __Secure-1PSIDTS cookie received")
+	err = errors.New("no new __Secure-1PSIDTS cookie received")
+	c.recordAuthError(err)
+	return err
 }
 
+// GetCookies returns a copy of the account cookies taken under their lock
 func (c *Client) GetCookies() *CookieStore {
 	c.cookies.mu.RLock()
 	defer c.cookies.mu.RUnlock()
-	
+
 	return &CookieStore{
 		Secure1PSID:   c.cookies.Secure1PSID,
 		Secure1PSIDTS: c.cookies.Secure1PSIDTS,
+		Secure1PSIDCC: c.cookies.Secure1PSIDCC,
 		UpdatedAt:     c.cookies.UpdatedAt,
 	}
 }
 
 func (c *Client) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
-	c.reqMu.Lock()
-	defer c.reqMu.Unlock()
+	stream, err := c.generate(ctx, prompt, false, options)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
 
+func (c *Client) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	return c.generate(ctx, prompt, true, options)
+}
+
+// generate starts a StreamGenerate request for a new conversation; live is set when the
+// caller streams the deltas
+func (c *Client) generate(ctx context.Context, prompt string, live bool, options []providers.GenerateOption) (<-chan providers.StreamChunk, error) {
 	config := &providers.GenerateConfig{
-		Model: "gemini-pro", // default
+		Model: "", // the web app default
 	}
 	for _, opt := range options {
 		opt(config)
 	}
 
-	if c.at == "" {
-		return nil, errors.New("client not initialized")
-	}
-
-	// Build request payload
-	inner := []interface{}{
-		[]interface{}{prompt},
-		nil,
-		nil,
+	model, err := c.resolveModel(ctx, config.Model)
+	if err != nil {
+		return nil, err
 	}
+	config.Locale = c.locale(config.Locale)
 
-	innerJSON, _ := json.Marshal(inner)
-	outer := []interface{}{nil, string(innerJSON)}
-	outerJSON, _ := json.Marshal(outer)
-
-	formData := map[string]string{
-		"at":    c.at,
-		"f.req": string(outerJSON),
-	}
+	return c.withReauth(ctx, func() (<-chan providers.StreamChunk, error) {
+		promptPart, err := c.buildPromptPart(ctx, prompt, config.Files)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.R().
-		SetFormData(formData).
-		SetQueryParam("at", c.at).
-		Post(EndpointGenerate)
+		return c.streamGenerate(ctx, model, generatePayload(promptPart, nil, model, config.Locale), config, live)
+	})
+}
 
-	if err != nil {
-		return nil, err
+// locale returns the requested locale, or the account's default when none was requested
+func (c *Client) locale(requested string) string {
+	if requested != "" {
+		return requested
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("generate failed with status: %d", resp.StatusCode)
+	if c.account.Locale != "" {
+		return c.account.Locale
 	}
-
-	return c.parseResponse(resp.String())
+	return defaultLocale
 }
 
 func (c *Client) StartChat(options ...providers.ChatOption) providers.ChatSession {
 	config := &providers.ChatConfig{
-		Model: "gemini-pro",
+		Model: "", // the web app default
 	}
 	for _, opt := range options {
 		opt(config)
@@ -449,8 +467,11 @@ func (c *Client) StartChat(options ...providers.ChatOption) providers.ChatSessio
 	}
 }
 
+// Close stops the background cookie rotation; closing a client more than once is a no-op
 func (c *Client) Close() error {
-	close(c.stopRefresh)
+	c.closeOnce.Do(func() {
+		close(c.stopRefresh)
+	})
 	c.mu.Lock()
 	c.healthy = false
 	c.mu.Unlock()
@@ -468,83 +489,30 @@ func (c *Client) IsHealthy() bool {
 }
 
 func (c *Client) ListModels() []providers.ModelInfo {
-	var models []providers.ModelInfo
-	for _, m := range providers.SupportedModels {
-		if m.Provider == "gemini" {
-			models = append(models, m)
-		}
-	}
-	return models
+	return append(upstreamModels(), c.gemModels()...)
 }
 
-// parseResponse parses Gemini's response format
-func (c *Client) parseResponse(text string) (*providers.Response, error) {
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		line = strings.TrimPrefix(line, ")]}'")
-
-		var root []interface{}
-		if err := json.Unmarshal([]byte(line), &root); err == nil {
-			for _, item := range root {
-				itemArray, ok := item.([]interface{})
-				if !ok || len(itemArray) < 3 {
-					continue
-				}
-
-				payloadStr, ok := itemArray[2].(string)
-				if !ok {
-					continue
-				}
-
-				var payload []interface{}
-				if err := json.Unmarshal([]byte(payloadStr), &payload); err != nil {
-					continue
-				}
-
-				if len(payload) > 4 {
-					candidates, ok := payload[4].([]interface{})
-					if ok && candidates != nil && len(candidates) > 0 {
-						firstCandidate, ok := candidates[0].([]interface{})
-						if ok && len(firstCandidate) >= 2 {
-							contentParts, ok := firstCandidate[1].([]interface{})
-							if ok && len(contentParts) > 0 {
-								resText, ok := contentParts[0].(string)
-								if ok {
-									// Extract conversation metadata if available
-									var cid, rid, rcid string
-									if len(firstCandidate) > 0 {
-										if id, ok := firstCandidate[0].(string); ok {
-											rcid = id
-										}
-									}
-									if len(payload) > 1 {
-										if id, ok := payload[1].(string); ok {
-											cid = id
-										}
-									}
-
-									return &providers.Response{
-										Text: resText,
-										Metadata: map[string]any{
-											"cid":  cid,
-											"rid":  rid,
-											"rcid": rcid,
-										},
-									}, nil
-								}
-							}
-						}
-					}
-				}
+// fill sets the __Secure-1PSID* values that are still empty from imported cookies
+func (cs *CookieStore) fill(cookies []*http.Cookie) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, ck := range cookies {
+		switch ck.Name {
+		case "__Secure-1PSID":
+			if cs.Secure1PSID == "" {
+				cs.Secure1PSID = ck.Value
+			}
+		case "__Secure-1PSIDTS":
+			if cs.Secure1PSIDTS == "" {
+				cs.Secure1PSIDTS = ck.Value
+			}
+		case "__Secure-1PSIDCC":
+			if cs.Secure1PSIDCC == "" {
+				cs.Secure1PSIDCC = ck.Value
 			}
 		}
 	}
-
-	return nil, fmt.Errorf("failed to parse response")
 }
 
 func (cs *CookieStore) ToHTTPCookies() []*http.Cookie {
@@ -597,65 +565,3 @@ func cleanCookie(v string) string {
 	v = strings.TrimSuffix(v, ";")
 	return v
 }
-
-// LoadCachedCookies attempts to read the saved 1PSIDTS from disk
-func (c *Client) LoadCachedCookies() (string, error) {
-	if c.cookies.Secure1PSID == "" {
-		return "", errors.New("no PSID available")
-	}
-
-	hash := sha256.Sum256([]byte(c.cookies.Secure1PSID))
-	filename := filepath.Join(".cookies", hex.EncodeToString(hash[:])+".txt")
-
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return "", err
-	}
-
-	ts := strings.TrimSpace(string(data))
-	if ts == "" {
-		return "", errors.New("empty cache file")
-	}
-	return ts, nil
-}
-
-// SaveCachedCookies writes the current 1PSIDTS to disk
-func (c *Client) SaveCachedCookies() error {
-	if c.cookies.Secure1PSID == "" || c.cookies.Secure1PSIDTS == "" {
-		return nil
-	}
-
-	// Create directory if not exists
-	if err := os.MkdirAll(".cookies", 0755); err != nil {
-		return err
-	}
-
-	hash := sha256.Sum256([]byte(c.cookies.Secure1PSID))
-	filename := filepath.Join(".cookies", hex.EncodeToString(hash[:])+".txt")
-
-	err := os.WriteFile(filename, []byte(c.cookies.Secure1PSIDTS), 0600)
-	if err == nil {
-		c.log.Debug("Saved __Secure-1PSIDTS to local cache for future use", zap.String("file", filename))
-	} else {
-		c.log.Warn("Failed to save cookies to cache", zap.String("file", filename), zap.Error(err))
-	}
-	return err
-}
-
-// ClearCookieCache deletes the cached cookie file for the current PSID
-func (c *Client) ClearCookieCache() error {
-	if c.cookies.Secure1PSID == "" {
-		return nil
-	}
-
-	hash := sha256.Sum256([]byte(c.cookies.Secure1PSID))
-	filename := filepath.Join(".cookies", hex.EncodeToString(hash[:])+".txt")
-
-	err := os.Remove(filename)
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-	
-	c.log.Debug("Cleared cookie cache", zap.String("file", filename))
-	return nil
-}
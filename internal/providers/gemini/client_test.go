@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+func TestCloseTwice(t *testing.T) {
+	c := NewClient(config.GeminiAccount{Secure1PSID: "psid"}, &config.Config{}, NewMemoryStore(), zap.NewNop())
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// The pool closes every account, which may already have been closed elsewhere
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if c.IsHealthy() {
+		t.Error("closed client reports healthy")
+	}
+}
+
+func TestGenerateWithoutModelUsesDefault(t *testing.T) {
+	c := NewClient(config.GeminiAccount{Secure1PSID: "psid"}, &config.Config{}, NewMemoryStore(), zap.NewNop())
+	// Keep the test offline: the client has no session and its last sign-in just failed
+	c.lastAuthErr = errors.New("offline")
+	c.lastAuthAttempt = time.Now()
+
+	_, err := c.GenerateContentStream(context.Background(), "hello")
+	if errors.Is(err, providers.ErrUnknownModel) {
+		t.Fatalf("request without a model was rejected: %v", err)
+	}
+	if !errors.Is(err, providers.ErrAuthExpired) {
+		t.Errorf("error %v, want the sign-in failure", err)
+	}
+
+	session := c.StartChat()
+	if model := session.GetMetadata().Model; model != "" {
+		t.Errorf("chat model %q, want the default", model)
+	}
+	if _, err := session.SendMessageStream(context.Background(), "hello"); errors.Is(err, providers.ErrUnknownModel) {
+		t.Errorf("chat message without a model was rejected: %v", err)
+	}
+}
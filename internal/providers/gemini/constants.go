@@ -6,12 +6,30 @@ const (
 	EndpointGenerate      = "https://gemini.google.com/_/BardChatUi/data/assistant.lamda.BardFrontendService/StreamGenerate"
 	EndpointRotateCookies = "https://accounts.google.com/RotateCookies"
 	EndpointBatchExec     = "https://gemini.google.com/_/BardChatUi/data/batchexecute"
+	EndpointUpload        = "https://content-push.googleapis.com/upload"
 )
 
+// UploadHeaders are sent with every file upload
+var UploadHeaders = map[string]string{
+	"Push-ID": "feeds/mcudyrk2a4khkz",
+}
+
+// DefaultHeaders are sent with requests to the Gemini web app's RPC endpoints.
+// The User-Agent and client hints come from the account's browser profile.
 var DefaultHeaders = map[string]string{
 	"Content-Type":  "application/x-www-form-urlencoded;charset=utf-8",
 	"Origin":        "https://gemini.google.com",
 	"Referer":       "https://gemini.google.com/",
-	"User-Agent":    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 	"X-Same-Domain": "1",
 }
+
+// NavigationHeaders are sent with page loads, like a browser opening the app in a tab
+var NavigationHeaders = map[string]string{
+	"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+	"Cache-Control":             "max-age=0",
+	"Sec-Fetch-Dest":            "document",
+	"Sec-Fetch-Mode":            "navigate",
+	"Sec-Fetch-Site":            "none",
+	"Sec-Fetch-User":            "?1",
+	"Upgrade-Insecure-Requests": "1",
+}
@@ -0,0 +1,161 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+// Conversation is a chat in the account's Gemini history
+type Conversation struct {
+	ID        string
+	Title     string
+	UpdatedAt time.Time
+}
+
+// ConversationTurn is one prompt of a conversation and the answer Gemini chose for it
+type ConversationTurn struct {
+	ResponseID  string
+	CandidateID string
+	Prompt      string
+	Response    string
+	CreatedAt   time.Time
+}
+
+// ListConversations returns the most recent conversations of the account, newest first
+func (c *Client) ListConversations(ctx context.Context, limit int) ([]Conversation, error) {
+	payload, _ := json.Marshal([]interface{}{limit, nil, []interface{}{0, nil, 1}})
+	answers, err := c.batchExecute(ctx, []rpcCall{{ID: rpcListConversations, Payload: string(payload)}})
+	if err != nil {
+		return nil, err
+	}
+
+	answer := answers["generic"]
+	if answer == "" {
+		return nil, nil
+	}
+	var root []interface{}
+	if err := json.Unmarshal([]byte(answer), &root); err != nil {
+		return nil, fmt.Errorf("%w: conversation list: %v", providers.ErrParseFailure, err)
+	}
+
+	list, _ := dig(root, 2).([]interface{})
+	conversations := make([]Conversation, 0, len(list))
+	for _, raw := range list {
+		id, _ := dig(raw, 0).(string)
+		if id == "" {
+			continue
+		}
+		title, _ := dig(raw, 1).(string)
+		conversations = append(conversations, Conversation{
+			ID:        id,
+			Title:     title,
+			UpdatedAt: parseTimestamp(dig(raw, 5)),
+		})
+	}
+	return conversations, nil
+}
+
+// ReadConversation returns the turns of a conversation, oldest first
+func (c *Client) ReadConversation(ctx context.Context, id string, limit int) ([]ConversationTurn, error) {
+	payload, _ := json.Marshal([]interface{}{id, limit, nil, 1, []interface{}{0}, []interface{}{4}, nil, 1})
+	answers, err := c.batchExecute(ctx, []rpcCall{{ID: rpcReadConversation, Payload: string(payload)}})
+	if err != nil {
+		return nil, err
+	}
+
+	answer := answers["generic"]
+	if answer == "" {
+		return nil, nil
+	}
+	var root []interface{}
+	if err := json.Unmarshal([]byte(answer), &root); err != nil {
+		return nil, fmt.Errorf("%w: conversation %s: %v", providers.ErrParseFailure, id, err)
+	}
+
+	list, _ := dig(root, 0).([]interface{})
+	turns := make([]ConversationTurn, 0, len(list))
+	for _, raw := range list {
+		rid, _ := dig(raw, 0, 1).(string)
+		prompt, _ := dig(raw, 2, 0, 0).(string)
+		rcid, _ := dig(raw, 3, 0, 0, 0).(string)
+		text, _ := dig(raw, 3, 0, 0, 1, 0).(string)
+		turns = append(turns, ConversationTurn{
+			ResponseID:  rid,
+			CandidateID: rcid,
+			Prompt:      prompt,
+			Response:    imagePlaceholderRe.ReplaceAllString(text, ""),
+			CreatedAt:   parseTimestamp(dig(raw, 4)),
+		})
+	}
+
+	sort.SliceStable(turns, func(i, j int) bool {
+		return turns[i].CreatedAt.Before(turns[j].CreatedAt)
+	})
+	return turns, nil
+}
+
+// DeleteConversation removes a conversation from the account's history
+func (c *Client) DeleteConversation(ctx context.Context, id string) error {
+	payload, _ := json.Marshal([]interface{}{id})
+	if _, err := c.batchExecute(ctx, []rpcCall{{ID: rpcDeleteConversation, Payload: string(payload)}}); err != nil {
+		return err
+	}
+
+	c.convMu.Lock()
+	delete(c.conversations, id)
+	c.convMu.Unlock()
+	return nil
+}
+
+// trackConversation records that the bridge used a conversation, so it can be deleted
+// once it has been idle for the conversation TTL
+func (c *Client) trackConversation(id string) {
+	if c.conversationTTL <= 0 || id == "" {
+		return
+	}
+	c.convMu.Lock()
+	c.conversations[id] = time.Now()
+	c.convMu.Unlock()
+}
+
+// deleteIdleConversations deletes the conversations the bridge used that have been idle
+// for the conversation TTL. Failed deletions are retried on the next run.
+func (c *Client) deleteIdleConversations(ctx context.Context) {
+	if c.conversationTTL <= 0 || !c.IsHealthy() {
+		return
+	}
+
+	c.convMu.Lock()
+	var idle []string
+	for id, used := range c.conversations {
+		if time.Since(used) >= c.conversationTTL {
+			idle = append(idle, id)
+		}
+	}
+	c.convMu.Unlock()
+
+	for _, id := range idle {
+		if err := c.DeleteConversation(ctx, id); err != nil {
+			c.log.Warn("Failed to delete idle conversation", zap.String("conversation", id), zap.Error(err))
+			continue
+		}
+		c.log.Debug("Deleted idle conversation", zap.String("conversation", id))
+	}
+}
+
+// parseTimestamp reads a [seconds, nanos] timestamp, returning the zero time when it is absent
+func parseTimestamp(v interface{}) time.Time {
+	seconds, ok := dig(v, 0).(float64)
+	if !ok {
+		return time.Time{}
+	}
+	nanos, _ := dig(v, 1).(float64)
+	return time.Unix(int64(seconds), int64(nanos))
+}
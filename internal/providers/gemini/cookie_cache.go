@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cookieCache is what the credential store keeps for an account
+type cookieCache struct {
+	UpdatedAt time.Time      `json:"updated_at"`
+	Cookies   []cachedCookie `json:"cookies"`
+}
+
+// value returns the value of the named cookie, or "" when the cache does not hold it
+func (cc *cookieCache) value(name string) string {
+	for _, ck := range cc.Cookies {
+		if ck.Name == name {
+			return ck.Value
+		}
+	}
+	return ""
+}
+
+// cacheKey identifies the account in the credential store by a hash of
+// __Secure-1PSID, so the cookie itself never appears in a file name
+func cacheKey(psid string) string {
+	hash := sha256.Sum256([]byte(psid))
+	return hex.EncodeToString(hash[:])
+}
+
+// legacyCacheFile is where older versions kept the plain __Secure-1PSIDTS value
+func (c *Client) legacyCacheFile(psid string) string {
+	return filepath.Join(c.cacheDir, cacheKey(psid)+".txt")
+}
+
+// LoadCachedCookies reads the cookies saved by a previous run. Caches written by older
+// versions, which only hold __Secure-1PSIDTS, are still read.
+func (c *Client) LoadCachedCookies() (*cookieCache, error) {
+	psid := c.GetCookies().Secure1PSID
+	if psid == "" {
+		return nil, errors.New("no PSID available")
+	}
+
+	data, err := c.store.Load(cacheKey(psid))
+	if err == nil {
+		var cache cookieCache
+		if err := json.Unmarshal(data, &cache); err != nil {
+			return nil, fmt.Errorf("invalid cookie cache: %w", err)
+		}
+		if len(cache.Cookies) == 0 {
+			return nil, errors.New("empty cache file")
+		}
+		return &cache, nil
+	}
+	if !errors.Is(err, ErrCredentialsNotFound) {
+		return nil, err
+	}
+
+	data, err = os.ReadFile(c.legacyCacheFile(psid))
+	if err != nil {
+		return nil, err
+	}
+	ts := strings.TrimSpace(string(data))
+	if ts == "" {
+		return nil, errors.New("empty cache file")
+	}
+	return &cookieCache{Cookies: []cachedCookie{{
+		Name:     "__Secure-1PSIDTS",
+		Value:    ts,
+		Domain:   "google.com",
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+	}}}, nil
+}
+
+// SaveCachedCookies writes the whole cookie jar to the credential store
+func (c *Client) SaveCachedCookies() error {
+	cookies := c.GetCookies()
+	if cookies.Secure1PSID == "" || cookies.Secure1PSIDTS == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cookieCache{
+		UpdatedAt: cookies.UpdatedAt,
+		Cookies:   c.jar.export(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := c.store.Save(cacheKey(cookies.Secure1PSID), data); err != nil {
+		c.log.Warn("Failed to save cookies to cache", zap.Error(err))
+		return err
+	}
+	c.log.Debug("Saved cookies to the credential store for future use")
+	// The legacy plaintext cache is superseded
+	_ = os.Remove(c.legacyCacheFile(cookies.Secure1PSID))
+	return nil
+}
+
+// ClearCookieCache deletes the stored cookies for the current PSID
+func (c *Client) ClearCookieCache() error {
+	psid := c.GetCookies().Secure1PSID
+	if psid == "" {
+		return nil
+	}
+
+	if err := c.store.Delete(cacheKey(psid)); err != nil {
+		return err
+	}
+	if err := os.Remove(c.legacyCacheFile(psid)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	c.log.Debug("Cleared cookie cache")
+	return nil
+}
@@ -0,0 +1,48 @@
+package gemini
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"ai-bridges/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestCookieCacheFollowsReplacedCookies(t *testing.T) {
+	store := NewMemoryStore()
+	account := config.GeminiAccount{Secure1PSID: "old-psid", Secure1PSIDTS: "old-psidts"}
+	c := NewClient(account, &config.Config{}, store, zap.NewNop())
+	c.jar.SetCookies(googleURL, c.cookies.ToHTTPCookies())
+
+	// Saving while the admin API swaps the cookies must neither race nor mix the two accounts
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = c.SaveCachedCookies()
+			_ = c.Status()
+		}()
+		go func() {
+			defer wg.Done()
+			c.cookies.replaceWith(&CookieStore{Secure1PSID: "new-psid", Secure1PSIDTS: "new-psidts", UpdatedAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+
+	if err := c.SaveCachedCookies(); err != nil {
+		t.Fatalf("SaveCachedCookies: %v", err)
+	}
+	if _, err := store.Load(cacheKey("new-psid")); err != nil {
+		t.Errorf("no cache for the new cookies: %v", err)
+	}
+	cache, err := c.LoadCachedCookies()
+	if err != nil {
+		t.Fatalf("LoadCachedCookies: %v", err)
+	}
+	if cache.UpdatedAt.IsZero() {
+		t.Error("cache lost the update time of the cookies")
+	}
+}
@@ -0,0 +1,208 @@
+package gemini
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCookies reads cookies in any of the formats people usually copy them in:
+// a Cookie request header ("a=1; b=2"), a Netscape cookies.txt file, or the JSON written
+// by browser cookie-export extensions. raw may also be the path of a file holding any
+// of these. Only Google cookies are returned. A Domain with a leading dot marks a cookie
+// sent to subdomains too; without one the cookie is host-only.
+func ParseCookies(raw string) ([]*http.Cookie, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("no cookies given")
+	}
+
+	if !isCookieJSON(raw) && !strings.ContainsAny(raw, "\n=") {
+		data, err := os.ReadFile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cookie file: %w", err)
+		}
+		raw = strings.TrimSpace(string(data))
+	}
+
+	var cookies []*http.Cookie
+	var err error
+	switch {
+	case isCookieJSON(raw):
+		cookies, err = parseCookieJSON(raw)
+	case isNetscapeCookies(raw):
+		cookies, err = parseNetscapeCookies(raw)
+	default:
+		cookies = parseCookieHeader(raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var google []*http.Cookie
+	for _, ck := range cookies {
+		if isGoogleDomain(ck.Domain) {
+			google = append(google, ck)
+		}
+	}
+	if len(google) == 0 {
+		return nil, errors.New("no Google cookies found")
+	}
+	return google, nil
+}
+
+// parseCookieHeader parses a Cookie request header. The header carries no domains,
+// so the cookies are scoped to all of google.com.
+func parseCookieHeader(raw string) []*http.Cookie {
+	raw = strings.TrimPrefix(raw, "Cookie:")
+	var cookies []*http.Cookie
+	for _, part := range strings.Split(raw, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{
+			Name:   strings.TrimSpace(name),
+			Value:  cleanCookie(value),
+			Domain: ".google.com",
+			Path:   "/",
+			Secure: strings.HasPrefix(name, "__Secure-"),
+		})
+	}
+	return cookies
+}
+
+// isCookieJSON reports whether raw looks like exported cookie JSON
+func isCookieJSON(raw string) bool {
+	return strings.HasPrefix(raw, "[") || strings.HasPrefix(raw, "{")
+}
+
+// isNetscapeCookies reports whether raw looks like a cookies.txt file
+func isNetscapeCookies(raw string) bool {
+	if strings.HasPrefix(raw, "# Netscape HTTP Cookie File") || strings.HasPrefix(raw, "# HTTP Cookie File") {
+		return true
+	}
+	line, _, _ := strings.Cut(raw, "\n")
+	return strings.Count(line, "\t") >= 6
+}
+
+// parseNetscapeCookies parses the tab-separated cookies.txt format:
+// domain, include subdomains, path, secure, expiry, name, value
+func parseNetscapeCookies(raw string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("invalid cookies.txt line %d: expected 7 tab-separated fields", n)
+		}
+
+		ck := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		ck.Domain = scopeDomain(ck.Domain, strings.EqualFold(fields[1], "TRUE"))
+		if expiry, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expiry > 0 {
+			ck.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, ck)
+	}
+	return cookies, scanner.Err()
+}
+
+// exportedCookie covers the fields used by common cookie-export extensions
+// (EditThisCookie, Cookie-Editor) and by browser automation storage files
+type exportedCookie struct {
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	Secure         bool     `json:"secure"`
+	HttpOnly       bool     `json:"httpOnly"`
+	HostOnly       bool     `json:"hostOnly"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	Expires        *float64 `json:"expires"`
+}
+
+// parseCookieJSON parses either a JSON array of cookies or an object with a "cookies" array
+func parseCookieJSON(raw string) ([]*http.Cookie, error) {
+	var exported []exportedCookie
+	if strings.HasPrefix(raw, "{") {
+		var wrapper struct {
+			Cookies []exportedCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal([]byte(raw), &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid cookie JSON: %w", err)
+		}
+		exported = wrapper.Cookies
+	} else if err := json.Unmarshal([]byte(raw), &exported); err != nil {
+		return nil, fmt.Errorf("invalid cookie JSON: %w", err)
+	}
+
+	cookies := make([]*http.Cookie, 0, len(exported))
+	for _, e := range exported {
+		if e.Name == "" {
+			continue
+		}
+		ck := &http.Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   e.Domain,
+			Path:     e.Path,
+			Secure:   e.Secure,
+			HttpOnly: e.HttpOnly,
+		}
+		ck.Domain = scopeDomain(ck.Domain, !e.HostOnly)
+		if ck.Path == "" {
+			ck.Path = "/"
+		}
+		expiry := e.ExpirationDate
+		if expiry == nil {
+			expiry = e.Expires
+		}
+		// Session cookies are exported with no expiry or with -1
+		if expiry != nil && *expiry > 0 {
+			sec, frac := math.Modf(*expiry)
+			ck.Expires = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		cookies = append(cookies, ck)
+	}
+	return cookies, nil
+}
+
+// scopeDomain writes a domain with a leading dot when the cookie is sent to subdomains
+func scopeDomain(domain string, subdomains bool) string {
+	domain = strings.TrimPrefix(domain, ".")
+	if subdomains {
+		return "." + domain
+	}
+	return domain
+}
+
+// isGoogleDomain reports whether a cookie domain belongs to google.com
+func isGoogleDomain(domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return domain == "google.com" || strings.HasSuffix(domain, ".google.com")
+}
@@ -0,0 +1,282 @@
+package gemini
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"ai-bridges/internal/config"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	CredentialStoreFile      = "file"
+	CredentialStoreEncrypted = "encrypted"
+	CredentialStoreMemory    = "memory"
+)
+
+// ErrCredentialsNotFound is returned by CredentialStore.Load when nothing is stored under a key
+var ErrCredentialsNotFound = errors.New("no stored credentials")
+
+// CredentialStore persists the saved cookie jar of each account between runs. Keys are
+// derived from the account's __Secure-1PSID and never contain the cookie itself.
+type CredentialStore interface {
+	Load(key string) ([]byte, error)
+	Save(key string, data []byte) error
+	Delete(key string) error
+}
+
+// NewCredentialStore creates the store selected by GEMINI_CREDENTIAL_STORE
+func NewCredentialStore(cfg *config.Config) (CredentialStore, error) {
+	switch cfg.Gemini.CredentialStore {
+	case CredentialStoreMemory:
+		return NewMemoryStore(), nil
+	case CredentialStoreEncrypted:
+		secret := cfg.Gemini.CredentialKey
+		if cfg.Gemini.CredentialKeyFile != "" {
+			data, err := os.ReadFile(cfg.Gemini.CredentialKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read credential key file: %w", err)
+			}
+			secret = strings.TrimSpace(string(data))
+		}
+		return NewEncryptedFileStore(cfg.Gemini.CookieCacheDir, secret)
+	default:
+		return NewFileStore(cfg.Gemini.CookieCacheDir), nil
+	}
+}
+
+// FileStore keeps each account's cookies in a plain JSON file
+type FileStore struct {
+	dir string
+	ext string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir, ext: ".json"}
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key+s.ext)
+}
+
+func (s *FileStore) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrCredentialsNotFound
+	}
+	return data, err
+}
+
+// Save replaces the file atomically so a crash mid-write never leaves a truncated file behind
+func (s *FileStore) Save(key string, data []byte) error {
+	return writeFileAtomic(s.path(key), data)
+}
+
+func (s *FileStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// EncryptedFileStore keeps each account's cookies in a file encrypted with AES-256-GCM.
+// The file key is bound to the ciphertext, so a file copied to another name fails to open.
+// Cookies left in plain JSON by a FileStore in the same directory are encrypted and removed.
+type EncryptedFileStore struct {
+	files *FileStore
+	plain *FileStore
+	aead  cipher.AEAD
+}
+
+// scrypt parameters of the key derivation
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	saltSize     = 16
+	saltFileName = "credential-key.salt"
+)
+
+// NewEncryptedFileStore derives the AES key from secret, which can be any string, with scrypt
+// and a random salt kept next to the encrypted files
+func NewEncryptedFileStore(dir, secret string) (*EncryptedFileStore, error) {
+	if secret == "" {
+		return nil, errors.New("encrypted credential store needs GEMINI_CREDENTIAL_KEY or GEMINI_CREDENTIAL_KEY_FILE")
+	}
+
+	salt, err := loadSalt(filepath.Join(dir, saltFileName))
+	if err != nil {
+		return nil, fmt.Errorf("credential key salt: %w", err)
+	}
+	key, err := scrypt.Key([]byte(secret), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedFileStore{
+		files: &FileStore{dir: dir, ext: ".enc"},
+		plain: NewFileStore(dir),
+		aead:  aead,
+	}, nil
+}
+
+// loadSalt reads the salt of the key derivation, creating it on first use
+func loadSalt(filename string) ([]byte, error) {
+	salt, err := os.ReadFile(filename)
+	if err == nil {
+		if len(salt) != saltSize {
+			return nil, fmt.Errorf("%s is corrupted", filename)
+		}
+		return salt, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	salt = make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filename, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Load decrypts the stored cookies. Plaintext files of a FileStore are encrypted on first use.
+func (s *EncryptedFileStore) Load(key string) ([]byte, error) {
+	data, err := s.files.Load(key)
+	if errors.Is(err, ErrCredentialsNotFound) {
+		return s.migratePlain(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return open(s.aead, key, data)
+}
+
+// migratePlain encrypts the cookies a FileStore left under key and deletes the plaintext file
+func (s *EncryptedFileStore) migratePlain(key string) ([]byte, error) {
+	data, err := s.plain.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Save(key, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Save encrypts data and removes any plaintext copy of it
+func (s *EncryptedFileStore) Save(key string, data []byte) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	if err := s.files.Save(key, s.aead.Seal(nonce, nonce, data, []byte(key))); err != nil {
+		return err
+	}
+	return s.plain.Delete(key)
+}
+
+func (s *EncryptedFileStore) Delete(key string) error {
+	if err := s.files.Delete(key); err != nil {
+		return err
+	}
+	return s.plain.Delete(key)
+}
+
+// open decrypts a nonce-prefixed file bound to key
+func open(aead cipher.AEAD, key string, data []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("encrypted credentials are truncated")
+	}
+	plain, err := aead.Open(nil, data[:size], data[size:], []byte(key))
+	if err != nil {
+		return nil, errors.New("failed to decrypt stored credentials (wrong key?)")
+	}
+	return plain, nil
+}
+
+// MemoryStore keeps cookies in memory only, so nothing is written to disk and
+// every restart goes through the full sign-in again
+type MemoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Load(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[key]
+	if !ok {
+		return nil, ErrCredentialsNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStore) Save(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames it into place
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
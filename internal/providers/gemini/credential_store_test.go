@@ -0,0 +1,85 @@
+package gemini
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewEncryptedFileStore(dir, "passphrase")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore: %v", err)
+	}
+	if err := store.Save("account", []byte(`{"cookies":[]}`)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A new store over the same directory reuses the salt and opens the file
+	reopened, err := NewEncryptedFileStore(dir, "passphrase")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore: %v", err)
+	}
+	data, err := reopened.Load("account")
+	if err != nil || string(data) != `{"cookies":[]}` {
+		t.Fatalf("Load = %q, %v", data, err)
+	}
+
+	wrongKey, err := NewEncryptedFileStore(dir, "other passphrase")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore: %v", err)
+	}
+	if _, err := wrongKey.Load("account"); err == nil {
+		t.Error("Load with the wrong passphrase succeeded")
+	}
+
+	if err := store.Delete("account"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Load("account"); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Errorf("Load after Delete: %v, want ErrCredentialsNotFound", err)
+	}
+}
+
+func TestEncryptedFileStoreMigratesPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewFileStore(dir).Save("account", []byte("plain cookies")); err != nil {
+		t.Fatalf("FileStore.Save: %v", err)
+	}
+
+	store, err := NewEncryptedFileStore(dir, "passphrase")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore: %v", err)
+	}
+	data, err := store.Load("account")
+	if err != nil || string(data) != "plain cookies" {
+		t.Fatalf("Load = %q, %v", data, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "account.json")); !os.IsNotExist(err) {
+		t.Errorf("plaintext file still present after migration (stat error %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "account.enc")); err != nil {
+		t.Errorf("no encrypted file after migration: %v", err)
+	}
+}
+
+func TestEncryptedFileStoreSaveRemovesPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewFileStore(dir).Save("account", []byte("old plain cookies")); err != nil {
+		t.Fatalf("FileStore.Save: %v", err)
+	}
+
+	store, err := NewEncryptedFileStore(dir, "passphrase")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore: %v", err)
+	}
+	if err := store.Save("account", []byte("new cookies")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "account.json")); !os.IsNotExist(err) {
+		t.Errorf("plaintext file still present after an encrypted save (stat error %v)", err)
+	}
+}
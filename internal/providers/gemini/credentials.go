@@ -0,0 +1,116 @@
+package gemini
+
+import (
+	"context"
+	"time"
+)
+
+// Credentials are the cookies an account signs in with. Cookies takes any format
+// ParseCookies understands; the separate values take precedence over it.
+type Credentials struct {
+	Secure1PSID   string
+	Secure1PSIDTS string
+	Secure1PSIDCC string
+	Cookies       string
+}
+
+// CredentialStatus describes the state of an account's cookies
+type CredentialStatus struct {
+	Healthy         bool
+	UpdatedAt       time.Time // when __Secure-1PSIDTS last changed
+	LastRotation    time.Time // zero until a rotation succeeds
+	NextRotation    time.Time // zero when automatic rotation is not running
+	LastAuthError   string
+	LastAuthErrorAt time.Time
+}
+
+// Status reports the state of the client's credentials
+func (c *Client) Status() CredentialStatus {
+	c.cookies.mu.RLock()
+	updatedAt := c.cookies.UpdatedAt
+	c.cookies.mu.RUnlock()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := CredentialStatus{
+		Healthy:      c.healthy,
+		UpdatedAt:    updatedAt,
+		LastRotation: c.lastRotation,
+		NextRotation: c.nextRotation,
+	}
+	if c.authError != nil {
+		status.LastAuthError = c.authError.Error()
+		status.LastAuthErrorAt = c.authErrorTime
+	}
+	return status
+}
+
+// UpdateCookies signs the client in with new cookies without interrupting requests in
+// flight. The new cookies are verified on a separate client first; only when that
+// succeeds are the cookie jar and session token swapped in.
+func (c *Client) UpdateCookies(ctx context.Context, creds Credentials) error {
+	c.mu.RLock()
+	account := c.account
+	c.mu.RUnlock()
+
+	account.Secure1PSID = creds.Secure1PSID
+	account.Secure1PSIDTS = creds.Secure1PSIDTS
+	account.Secure1PSIDCC = creds.Secure1PSIDCC
+	account.Cookies = creds.Cookies
+
+	staging := NewClient(account, c.cfg, c.store, c.log)
+	staging.autoRefresh = false
+	if err := staging.Init(ctx); err != nil {
+		return err
+	}
+
+	// Keep re-authentication and rotation out while the credentials change
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.rotateMu.Lock()
+	defer c.rotateMu.Unlock()
+
+	c.jar.replaceWith(staging.jar)
+	c.cookies.replaceWith(staging.cookies)
+
+	c.mu.Lock()
+	c.account = account
+	c.rawCookies = account.Cookies
+	c.at = staging.at
+	c.authGen++
+	c.healthy = true
+	c.authError = nil
+	c.mu.Unlock()
+	c.lastAuthErr = nil
+
+	c.log.Info("Gemini cookies updated")
+	c.ensureAutoRefresh()
+	return nil
+}
+
+// recordAuthError remembers the latest authentication failure for Status
+func (c *Client) recordAuthError(err error) {
+	c.mu.Lock()
+	c.authError = err
+	c.authErrorTime = time.Now()
+	c.mu.Unlock()
+}
+
+// scheduleRotation records when the automatic rotation runs next
+func (c *Client) scheduleRotation() {
+	c.mu.Lock()
+	c.nextRotation = time.Now().Add(c.refreshInterval)
+	c.mu.Unlock()
+}
+
+// replaceWith copies the cookies of another store
+func (cs *CookieStore) replaceWith(other *CookieStore) {
+	other.mu.RLock()
+	psid, psidts, psidcc, updatedAt := other.Secure1PSID, other.Secure1PSIDTS, other.Secure1PSIDCC, other.UpdatedAt
+	other.mu.RUnlock()
+
+	cs.mu.Lock()
+	cs.Secure1PSID, cs.Secure1PSIDTS, cs.Secure1PSIDCC, cs.UpdatedAt = psid, psidts, psidcc, updatedAt
+	cs.mu.Unlock()
+}
@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"fmt"
+	"net/http"
+
+	"ai-bridges/internal/providers"
+)
+
+// Error codes the Gemini web app puts in a response frame instead of an answer
+const (
+	errCodeUsageLimitExceeded   = 1037
+	errCodeModelInconsistent    = 1050
+	errCodeModelHeaderInvalid   = 1052
+	errCodeIPTemporarilyBlocked = 1060
+)
+
+// errEmptyAnswer is returned for an answer without text or images, which is how Gemini web
+// answers prompts its safety filters refuse
+var errEmptyAnswer = fmt.Errorf("%w: Gemini returned an empty answer", providers.ErrContentBlocked)
+
+// statusError classifies a non-200 upstream response
+func statusError(op string, status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %s failed with status: %d", providers.ErrAuthExpired, op, status)
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s failed with status: %d", providers.ErrRateLimited, op, status)
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		return fmt.Errorf("%w: %s failed with status: %d", providers.ErrTimeout, op, status)
+	default:
+		return fmt.Errorf("%w: %s failed with status: %d", providers.ErrUpstreamUnavailable, op, status)
+	}
+}
+
+// frameError classifies the error code of a response frame that carries no answer
+func frameError(code int) error {
+	switch code {
+	case errCodeUsageLimitExceeded:
+		return fmt.Errorf("%w: usage limit of the Gemini account exceeded (code %d)", providers.ErrRateLimited, code)
+	case errCodeIPTemporarilyBlocked:
+		return fmt.Errorf("%w: IP temporarily blocked by Gemini (code %d)", providers.ErrRateLimited, code)
+	case errCodeModelInconsistent:
+		return fmt.Errorf("%w: model does not match the one used earlier in the conversation (code %d)", providers.ErrUnknownModel, code)
+	case errCodeModelHeaderInvalid:
+		return fmt.Errorf("%w: model is not available to this account (code %d)", providers.ErrUnknownModel, code)
+	default:
+		return fmt.Errorf("%w: Gemini returned error code %d", providers.ErrUpstreamUnavailable, code)
+	}
+}
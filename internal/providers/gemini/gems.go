@@ -0,0 +1,218 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// gemsCacheTTL is how long the list of an account's Gems is reused
+	gemsCacheTTL = 10 * time.Minute
+
+	// gemsRetryInterval is how soon an unknown Gem may trigger a fresh listing
+	gemsRetryInterval = time.Minute
+
+	// gemsFetchTimeout bounds listings made where the caller has no deadline
+	gemsFetchTimeout = 10 * time.Second
+)
+
+// Gem is a predefined or custom Gem of an account
+type Gem struct {
+	ID          string
+	Name        string
+	Description string
+	Prompt      string
+	Predefined  bool
+}
+
+// IsGemModel reports whether a model ID selects a Gem
+func IsGemModel(id string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(strings.TrimSpace(id), "models/"), providers.GemModelPrefix)
+}
+
+// ListGems fetches the predefined and custom Gems of the account
+func (c *Client) ListGems(ctx context.Context) ([]Gem, error) {
+	answers, err := c.batchExecute(ctx, []rpcCall{
+		{ID: rpcListGems, Payload: "[3]", Identifier: "system"},
+		{ID: rpcListGems, Payload: "[2]", Identifier: "custom"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	gems := parseGems(answers["system"], true)
+	gems = append(gems, parseGems(answers["custom"], false)...)
+
+	c.gemsMu.Lock()
+	c.gems = gems
+	c.gemsFetched = time.Now()
+	c.gemsMu.Unlock()
+	return gems, nil
+}
+
+// cachedGems returns the account's Gems, listing them again once the cache is older
+// than maxAge. A failed listing keeps serving the previous list.
+func (c *Client) cachedGems(ctx context.Context, maxAge time.Duration) []Gem {
+	c.gemsMu.Lock()
+	gems, fetched := c.gems, c.gemsFetched
+	c.gemsMu.Unlock()
+
+	if time.Since(fetched) < maxAge || !c.IsHealthy() {
+		return gems
+	}
+
+	fresh, err := c.ListGems(ctx)
+	if err != nil {
+		c.log.Warn("Failed to list Gems", zap.Error(err))
+		c.gemsMu.Lock()
+		c.gemsFetched = time.Now()
+		c.gemsMu.Unlock()
+		return gems
+	}
+	return fresh
+}
+
+// findGem looks up a Gem by ID or alias, listing the Gems again if it is not known yet
+func (c *Client) findGem(ctx context.Context, name string) (Gem, bool) {
+	if gem, ok := lookupGem(c.cachedGems(ctx, gemsCacheTTL), name); ok {
+		return gem, true
+	}
+	return lookupGem(c.cachedGems(ctx, gemsRetryInterval), name)
+}
+
+// resolveModel looks up a public model ID, including the Gems of the account.
+// Gems run on the web app's default model.
+func (c *Client) resolveModel(ctx context.Context, id string) (Model, error) {
+	name, ok := strings.CutPrefix(strings.TrimPrefix(strings.TrimSpace(id), "models/"), providers.GemModelPrefix)
+	if !ok {
+		return ResolveModel(id)
+	}
+
+	gem, ok := c.findGem(ctx, name)
+	if !ok {
+		return Model{}, fmt.Errorf("%w: Gem %q is not available to this account", providers.ErrUnknownModel, name)
+	}
+	model := registeredModels[defaultModel]
+	model.ID = providers.GemModelPrefix + gem.ID
+	model.GemID = gem.ID
+	return model, nil
+}
+
+// gemModels lists the account's Gems as models
+func (c *Client) gemModels() []providers.ModelInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), gemsFetchTimeout)
+	defer cancel()
+
+	gems := c.cachedGems(ctx, gemsCacheTTL)
+	aliases := gemAliases(gems)
+	models := make([]providers.ModelInfo, 0, len(gems))
+	for _, gem := range gems {
+		owner := "user"
+		if gem.Predefined {
+			owner = "google"
+		}
+		models = append(models, providers.ModelInfo{
+			ID:          providers.GemModelPrefix + aliases[gem.ID],
+			OwnedBy:     owner,
+			Provider:    "gemini",
+			Name:        gem.Name,
+			Description: gem.Description,
+		})
+	}
+	return models
+}
+
+// lookupGem finds a Gem by ID or by its alias
+func lookupGem(gems []Gem, name string) (Gem, bool) {
+	for _, gem := range gems {
+		if gem.ID == name {
+			return gem, true
+		}
+	}
+	aliases := gemAliases(gems)
+	for _, gem := range gems {
+		if aliases[gem.ID] == name {
+			return gem, true
+		}
+	}
+	return Gem{}, false
+}
+
+// gemAliases maps each Gem ID to the name it is listed under: a slug of the Gem's name,
+// or the ID itself when the slug is empty or shared with another Gem
+func gemAliases(gems []Gem) map[string]string {
+	count := make(map[string]int)
+	for _, gem := range gems {
+		count[slugify(gem.Name)]++
+	}
+	ids := make(map[string]bool)
+	for _, gem := range gems {
+		ids[gem.ID] = true
+	}
+
+	aliases := make(map[string]string, len(gems))
+	for _, gem := range gems {
+		slug := slugify(gem.Name)
+		if slug == "" || count[slug] > 1 || ids[slug] {
+			slug = gem.ID
+		}
+		aliases[gem.ID] = slug
+	}
+	return aliases
+}
+
+// slugify lowercases a name and joins its words with dashes
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// parseGems reads the Gems from a list Gems answer: [_, _, [[id, [name, description], [prompt]], ...]]
+func parseGems(payload string, predefined bool) []Gem {
+	if payload == "" {
+		return nil
+	}
+	var root []interface{}
+	if err := json.Unmarshal([]byte(payload), &root); err != nil {
+		return nil
+	}
+
+	list, _ := dig(root, 2).([]interface{})
+	gems := make([]Gem, 0, len(list))
+	for _, raw := range list {
+		id, _ := dig(raw, 0).(string)
+		if id == "" {
+			continue
+		}
+		name, _ := dig(raw, 1, 0).(string)
+		description, _ := dig(raw, 1, 1).(string)
+		prompt, _ := dig(raw, 2, 0).(string)
+		gems = append(gems, Gem{
+			ID:          id,
+			Name:        name,
+			Description: description,
+			Prompt:      prompt,
+			Predefined:  predefined,
+		})
+	}
+	return gems
+}
@@ -0,0 +1,162 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cookieJar is the jar shared by all upstream calls of an account. Besides serving
+// cookies like any jar, it remembers every cookie it was given so the whole jar can be
+// saved to disk and restored after a restart.
+type cookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	entries map[string]cachedCookie // keyed by domain, path and name
+}
+
+// cachedCookie is the form a cookie takes in the cookie cache file
+type cachedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"http_only"`
+	HostOnly bool      `json:"host_only"` // only sent to Domain itself, not its subdomains
+}
+
+func newCookieJar() *cookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &cookieJar{jar: jar, entries: map[string]cachedCookie{}}
+}
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, ck := range cookies {
+		entry := cachedCookie{
+			Name:     ck.Name,
+			Value:    ck.Value,
+			Domain:   ck.Domain,
+			Path:     ck.Path,
+			Secure:   ck.Secure,
+			HttpOnly: ck.HttpOnly,
+		}
+		if len(entry.Domain) > 0 && entry.Domain[0] == '.' {
+			entry.Domain = entry.Domain[1:]
+		}
+		if entry.Domain == "" {
+			entry.Domain = u.Hostname()
+			entry.HostOnly = true
+		}
+		if entry.Path == "" {
+			entry.Path = "/"
+		}
+
+		switch {
+		case ck.MaxAge < 0:
+			entry.Expires = now
+		case ck.MaxAge > 0:
+			entry.Expires = now.Add(time.Duration(ck.MaxAge) * time.Second)
+		case !ck.Expires.IsZero():
+			entry.Expires = ck.Expires
+		}
+
+		key := entry.Domain + ";" + entry.Path + ";" + entry.Name
+		if !entry.Expires.IsZero() && !entry.Expires.After(now) {
+			delete(j.entries, key)
+			continue
+		}
+		j.entries[key] = entry
+	}
+}
+
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	jar := j.jar
+	j.mu.Unlock()
+	return jar.Cookies(u)
+}
+
+// replaceWith swaps in the contents of another jar. Clients holding this jar send the
+// new cookies from their next request on.
+func (j *cookieJar) replaceWith(other *cookieJar) {
+	other.mu.Lock()
+	jar := other.jar
+	entries := make(map[string]cachedCookie, len(other.entries))
+	for key, entry := range other.entries {
+		entries[key] = entry
+	}
+	other.mu.Unlock()
+
+	j.mu.Lock()
+	j.jar = jar
+	j.entries = entries
+	j.mu.Unlock()
+}
+
+// export returns every cookie in the jar that has not expired
+func (j *cookieJar) export() []cachedCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	cookies := make([]cachedCookie, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if entry.Expires.IsZero() || entry.Expires.After(now) {
+			cookies = append(cookies, entry)
+		}
+	}
+	return cookies
+}
+
+// restore adds previously exported cookies to the jar
+func (j *cookieJar) restore(cookies []cachedCookie) {
+	for _, entry := range cookies {
+		ck := &http.Cookie{
+			Name:     entry.Name,
+			Value:    entry.Value,
+			Path:     entry.Path,
+			Expires:  entry.Expires,
+			Secure:   entry.Secure,
+			HttpOnly: entry.HttpOnly,
+		}
+		if !entry.HostOnly {
+			ck.Domain = entry.Domain
+		}
+		j.SetCookies(&url.URL{Scheme: "https", Host: entry.Domain, Path: entry.Path}, []*http.Cookie{ck})
+	}
+}
+
+// add puts cookies into the jar under their own domains. A Domain without a leading
+// dot makes a host-only cookie.
+func (j *cookieJar) add(cookies []*http.Cookie) {
+	for _, ck := range cookies {
+		u := &url.URL{Scheme: "https", Host: strings.TrimPrefix(ck.Domain, "."), Path: "/"}
+		ck := *ck
+		if !strings.HasPrefix(ck.Domain, ".") {
+			ck.Domain = ""
+		}
+		j.SetCookies(u, []*http.Cookie{&ck})
+	}
+}
+
+// has reports whether the jar would send a cookie with the given name to u
+func (j *cookieJar) has(u *url.URL, name string) bool {
+	for _, ck := range j.Cookies(u) {
+		if ck.Name == name {
+			return true
+		}
+	}
+	return false
+}
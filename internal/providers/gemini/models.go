@@ -0,0 +1,73 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// modelHeader is the request header the Gemini web frontend uses to select a model
+const modelHeader = "x-goog-ext-525001261-jspb"
+
+// defaultModel is served by whatever model the web app picks when no selector is sent
+const defaultModel = "unspecified"
+
+// Model maps a public model ID to the selector the Gemini web frontend sends upstream
+type Model struct {
+	ID       string
+	Selector string // value of the model selector header, empty for the web app default
+	GemID    string // Gem the request is sent to, empty for plain chats
+}
+
+// Headers returns the headers that select this model upstream
+func (m Model) Headers() map[string]string {
+	if m.Selector == "" {
+		return nil
+	}
+	return map[string]string{modelHeader: m.Selector}
+}
+
+// registeredModels lists the models the web app can be asked for explicitly
+var registeredModels = map[string]Model{
+	defaultModel: {ID: defaultModel},
+	"gemini-2.5-flash": {
+		ID:       "gemini-2.5-flash",
+		Selector: `[1,null,null,null,"9ec249fc9ad08861",null,null,0,[4]]`,
+	},
+	"gemini-2.5-pro": {
+		ID:       "gemini-2.5-pro",
+		Selector: `[1,null,null,null,"4af6c7f5da75d65d",null,null,0,[4]]`,
+	},
+	"gemini-3.0-pro": {
+		ID:       "gemini-3.0-pro",
+		Selector: `[1,null,null,null,"9d8ca3786ebdfbea",null,null,0,[4]]`,
+	},
+}
+
+// ResolveModel looks up an upstream model ID. An empty ID selects the web app default.
+// Public IDs are mapped to these by the model routing table.
+func ResolveModel(id string) (Model, error) {
+	id = strings.TrimPrefix(strings.TrimSpace(id), "models/")
+	if id == "" {
+		return registeredModels[defaultModel], nil
+	}
+	if model, ok := registeredModels[id]; ok {
+		return model, nil
+	}
+	return Model{}, fmt.Errorf("%w: %q is not a supported Gemini model", providers.ErrUnknownModel, id)
+}
+
+// upstreamModels lists the models that can be selected explicitly, in ID order
+func upstreamModels() []providers.ModelInfo {
+	models := make([]providers.ModelInfo, 0, len(registeredModels))
+	for id := range registeredModels {
+		if id == defaultModel {
+			continue
+		}
+		models = append(models, providers.ModelInfo{ID: id, OwnedBy: "google", Provider: "gemini"})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models
+}
@@ -0,0 +1,239 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// imagePlaceholderPrefix starts the placeholder links Gemini puts in the text where images are shown
+const imagePlaceholderPrefix = "http://googleusercontent.com/"
+
+var (
+	// imagePlaceholderRe matches a complete image placeholder link
+	imagePlaceholderRe = regexp.MustCompile(`http://googleusercontent\.com/\w+/\d+\n*`)
+
+	// partialPlaceholderRe matches a placeholder link that is still being streamed
+	partialPlaceholderRe = regexp.MustCompile(`^http://googleusercontent\.com/\w*/?$`)
+)
+
+// parseFrame parses a single line of Gemini's StreamGenerate response.
+// Each frame carries the full text generated so far, not just the new part.
+func parseFrame(line string) (*providers.Response, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, false
+	}
+	line = strings.TrimPrefix(line, ")]}'")
+
+	var root []interface{}
+	if err := json.Unmarshal([]byte(line), &root); err != nil {
+		return nil, false
+	}
+
+	for _, item := range root {
+		payloadStr, ok := dig(item, 2).(string)
+		if !ok {
+			continue
+		}
+
+		var payload []interface{}
+		if err := json.Unmarshal([]byte(payloadStr), &payload); err != nil {
+			continue
+		}
+
+		rawCandidates, _ := dig(payload, 4).([]interface{})
+		var candidates []providers.Candidate
+		for _, raw := range rawCandidates {
+			candidate, ok := raw.([]interface{})
+			if !ok {
+				continue
+			}
+			// A refused prompt gets a candidate without text
+			rcid, _ := dig(candidate, 0).(string)
+			text, ok := dig(candidate, 1, 0).(string)
+			if !ok && rcid == "" {
+				continue
+			}
+			thoughts, _ := dig(candidate, 37, 0, 0).(string)
+			content := imagePlaceholderRe.ReplaceAllString(text, "")
+			candidates = append(candidates, providers.Candidate{
+				ID:        rcid,
+				Content:   content,
+				Thoughts:  thoughts,
+				Images:    parseImages(candidate),
+				Citations: parseCitations(candidate, content),
+			})
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// Conversation metadata is [cid, rid]; older payloads only carry the cid
+		var cid, rid string
+		switch meta := dig(payload, 1).(type) {
+		case string:
+			cid = meta
+		case []interface{}:
+			cid, _ = dig(meta, 0).(string)
+			rid, _ = dig(meta, 1).(string)
+		}
+
+		chosen := candidates[0]
+		return &providers.Response{
+			Text:           chosen.Content,
+			Thoughts:       chosen.Thoughts,
+			Images:         chosen.Images,
+			Citations:      chosen.Citations,
+			Candidates:     candidates,
+			ChosenIndex:    0,
+			ConversationID: cid,
+			ResponseID:     rid,
+			Metadata: map[string]any{
+				"cid":  cid,
+				"rid":  rid,
+				"rcid": chosen.ID,
+			},
+		}, true
+	}
+
+	return nil, false
+}
+
+// parseErrorCode reads the error code Gemini sends in place of an answer, e.g. when
+// the account hit its usage limit
+func parseErrorCode(line string) (int, bool) {
+	line = strings.TrimPrefix(strings.TrimSpace(line), ")]}'")
+	if line == "" {
+		return 0, false
+	}
+
+	var root []interface{}
+	if err := json.Unmarshal([]byte(line), &root); err != nil {
+		return 0, false
+	}
+	for _, item := range root {
+		if code, ok := dig(item, 5, 2, 0, 1, 0).(float64); ok {
+			return int(code), true
+		}
+	}
+	return 0, false
+}
+
+// parseImages extracts web search images and generated images from a candidate
+func parseImages(candidate []interface{}) []providers.Image {
+	var images []providers.Image
+
+	webImages, _ := dig(candidate, 12, 1).([]interface{})
+	for _, img := range webImages {
+		url, _ := dig(img, 0, 0, 0).(string)
+		if url == "" {
+			continue
+		}
+		title, _ := dig(img, 7, 0).(string)
+		alt, _ := dig(img, 0, 4).(string)
+		width, height := imageSize(dig(img, 0, 2))
+		images = append(images, providers.Image{
+			URL:     url,
+			Title:   title,
+			AltText: alt,
+			Width:   width,
+			Height:  height,
+		})
+	}
+
+	generated, _ := dig(candidate, 12, 7, 0).([]interface{})
+	for i, img := range generated {
+		url, _ := dig(img, 0, 3, 3).(string)
+		if url == "" {
+			continue
+		}
+		title := fmt.Sprintf("[Generated Image %d]", i+1)
+		if id, ok := dig(img, 3, 6).(float64); ok {
+			title = fmt.Sprintf("[Generated Image %d]", int(id))
+		}
+		alt, _ := dig(img, 3, 5, 0).(string)
+		width, height := imageSize(dig(img, 0, 3, 2))
+		images = append(images, providers.Image{
+			URL:     url,
+			Title:   title,
+			AltText: alt,
+			Width:   width,
+			Height:  height,
+		})
+	}
+
+	return images
+}
+
+// parseCitations extracts the search grounding sources of a candidate. Each source entry
+// starts with the [start, end] byte span it supports and holds the source URL and title
+// further down; spans past the end of text are dropped and the source kept unspanned.
+func parseCitations(candidate []interface{}, text string) []providers.Citation {
+	entries, _ := dig(candidate, 2, 0).([]interface{})
+
+	var citations []providers.Citation
+	seen := make(map[providers.Citation]bool)
+	for _, entry := range entries {
+		url, title := findSource(entry)
+		if url == "" {
+			continue
+		}
+
+		citation := providers.Citation{URL: url, Title: title}
+		start, okStart := dig(entry, 0, 0).(float64)
+		end, okEnd := dig(entry, 0, 1).(float64)
+		if okStart && okEnd && start >= 0 && start < end && int(end) <= len(text) {
+			citation.StartIndex = int(start)
+			citation.EndIndex = int(end)
+		}
+		if !seen[citation] {
+			seen[citation] = true
+			citations = append(citations, citation)
+		}
+	}
+	return citations
+}
+
+// findSource returns the first URL nested in a citation entry and the title next to it
+func findSource(v interface{}) (string, string) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return "", ""
+	}
+	for i, item := range arr {
+		if s, ok := item.(string); ok && (strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")) {
+			title := ""
+			if i+1 < len(arr) {
+				title, _ = arr[i+1].(string)
+			}
+			return s, title
+		}
+		if url, title := findSource(item); url != "" {
+			return url, title
+		}
+	}
+	return "", ""
+}
+
+// imageSize reads a [width, height] pair, returning zeros when it is absent
+func imageSize(v interface{}) (int, int) {
+	width, _ := dig(v, 0).(float64)
+	height, _ := dig(v, 1).(float64)
+	return int(width), int(height)
+}
+
+// dig walks nested JSON arrays by index and returns nil when any step is missing
+func dig(v interface{}, path ...int) interface{} {
+	for _, i := range path {
+		arr, ok := v.([]interface{})
+		if !ok || i < 0 || i >= len(arr) {
+			return nil
+		}
+		v = arr[i]
+	}
+	return v
+}
@@ -0,0 +1,643 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+const (
+	StrategyRoundRobin    = "round_robin"
+	StrategyLeastBusy     = "least_busy"
+	StrategyLowestLatency = "lowest_latency"
+
+	// latencyWeight is the weight of the newest sample in the latency moving average
+	latencyWeight = 0.3
+
+	// conversationCleanupInterval is how often idle conversations are looked for
+	conversationCleanupInterval = time.Minute
+)
+
+// errNoHealthyAccounts is returned when every account is ejected
+var errNoHealthyAccounts = fmt.Errorf("%w: no healthy Gemini accounts available", providers.ErrUpstreamUnavailable)
+
+// errNoAccounts is returned when no Gemini account is configured
+var errNoAccounts = fmt.Errorf("%w: no Gemini accounts configured", providers.ErrUpstreamUnavailable)
+
+// ErrUnknownAccount is returned by the account management methods for an unknown account ID
+var ErrUnknownAccount = errors.New("unknown account")
+
+// Pool spreads requests across several Gemini accounts. Accounts that keep failing
+// authentication are ejected and periodically re-authenticated in the background.
+type Pool struct {
+	accounts         []*account
+	strategy         string
+	maxAuthFailures  int
+	recoveryInterval time.Duration
+	conversationTTL  time.Duration
+	next             atomic.Uint64
+	log              *zap.Logger
+	stopRecovery     chan struct{} // closed on Close to stop the background loops
+	closeOnce        sync.Once
+}
+
+// account tracks the load and health of one pooled client
+type account struct {
+	id       int
+	client   *Client
+	inflight atomic.Int64
+
+	mu       sync.Mutex
+	failures int
+	ejected  bool
+	latency  time.Duration
+
+	// gemsMu serializes Gem lookups, so concurrent requests for a Gem list the account's
+	// Gems at most once, and guards their cached answers
+	gemsMu sync.Mutex
+	gems   map[string]gemLookup
+}
+
+// gemLookup is the cached answer to whether an account has a Gem
+type gemLookup struct {
+	found   bool
+	checked time.Time
+}
+
+// NewPool creates one client per configured account. All accounts save their cookies in store.
+func NewPool(cfg *config.Config, store CredentialStore, log *zap.Logger) *Pool {
+	pool := &Pool{
+		strategy:         cfg.Gemini.Pool.Strategy,
+		maxAuthFailures:  cfg.Gemini.Pool.MaxAuthFailures,
+		recoveryInterval: time.Duration(cfg.Gemini.Pool.RecoveryInterval) * time.Minute,
+		conversationTTL:  time.Duration(cfg.Gemini.ConversationTTL) * time.Minute,
+		log:              log,
+		stopRecovery:     make(chan struct{}),
+	}
+	if pool.maxAuthFailures <= 0 {
+		pool.maxAuthFailures = 1
+	}
+	if pool.recoveryInterval <= 0 {
+		pool.recoveryInterval = time.Minute
+	}
+
+	for i, acc := range cfg.Gemini.Accounts {
+		pool.accounts = append(pool.accounts, &account{
+			id:     i + 1,
+			client: NewClient(acc, cfg, store, log.With(zap.Int("account", i+1))),
+		})
+	}
+	return pool
+}
+
+// Init initializes every account. It only fails when no account could be initialized;
+// accounts that fail are ejected until they re-authenticate.
+func (p *Pool) Init(ctx context.Context) error {
+	if len(p.accounts) == 0 {
+		return errNoAccounts
+	}
+
+	var errs []error
+	for _, acc := range p.accounts {
+		if err := acc.client.Init(ctx); err != nil {
+			p.log.Warn("Gemini account failed to initialize, ejecting", zap.Int("account", acc.id), zap.Error(err))
+			acc.mu.Lock()
+			acc.ejected = true
+			acc.mu.Unlock()
+			errs = append(errs, fmt.Errorf("account %d: %w", acc.id, err))
+		}
+	}
+
+	go p.startRecovery()
+	if p.conversationTTL > 0 {
+		go p.startConversationCleanup()
+	}
+
+	if len(errs) == len(p.accounts) {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		p.log.Info("Gemini pool initialized with some accounts ejected",
+			zap.Int("active", len(p.accounts)-len(errs)), zap.Int("total", len(p.accounts)))
+	}
+	return nil
+}
+
+func (p *Pool) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := p.GenerateContentStream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+// GenerateContentStream picks an account and streams the response from it.
+// If an account fails authentication or is rate limited before streaming starts, the next one is tried.
+// Gems are only served by the accounts that have them. Requests pinned to an account only go to it.
+func (p *Pool) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	config := &providers.GenerateConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	var lastErr error
+	tried := p.missingGem(ctx, config.Model)
+	if len(p.accounts) > 0 && len(tried) == len(p.accounts) {
+		return nil, fmt.Errorf("%w: no account has the Gem %q", providers.ErrUnknownModel, config.Model)
+	}
+	if config.Account != 0 {
+		if p.accountByID(config.Account) == nil {
+			return nil, fmt.Errorf("%w: %w %d", providers.ErrUpstreamUnavailable, ErrUnknownAccount, config.Account)
+		}
+		if tried[config.Account] {
+			return nil, fmt.Errorf("%w: account %d does not have the Gem %q", providers.ErrUnknownModel, config.Account, config.Model)
+		}
+		for _, acc := range p.accounts {
+			if acc.id != config.Account {
+				tried[acc.id] = true
+			}
+		}
+	}
+	for range p.accounts {
+		acc, err := p.pick(tried)
+		if err != nil {
+			break
+		}
+		tried[acc.id] = true
+
+		stream, err := p.track(ctx, acc, func() (<-chan providers.StreamChunk, error) {
+			return acc.client.GenerateContentStream(ctx, prompt, options...)
+		})
+		if err == nil {
+			return stream, nil
+		}
+		lastErr = err
+		if !errors.Is(err, providers.ErrAuthExpired) && !errors.Is(err, providers.ErrRateLimited) {
+			return nil, err
+		}
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, errNoHealthyAccounts
+}
+
+// StartChat binds a chat session to one account. Restored sessions go back to the
+// account recorded in their metadata.
+func (p *Pool) StartChat(options ...providers.ChatOption) providers.ChatSession {
+	config := &providers.ChatConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	if len(p.accounts) == 0 {
+		return &unavailableSession{model: config.Model, err: errNoAccounts}
+	}
+
+	var acc *account
+	if config.Metadata != nil {
+		acc = p.accountByID(accountFromMetadata(config.Metadata))
+	}
+	if acc == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), gemsFetchTimeout)
+		exclude := p.missingGem(ctx, config.Model)
+		cancel()
+
+		var err error
+		if acc, err = p.pick(exclude); err != nil {
+			// Every account is ejected; fall back to the first one so the
+			// session reports the authentication error on use.
+			acc = p.accounts[0]
+		}
+	}
+
+	return &poolSession{
+		ChatSession: acc.client.StartChat(options...),
+		pool:        p,
+		account:     acc,
+	}
+}
+
+func (p *Pool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.stopRecovery)
+	})
+	for _, acc := range p.accounts {
+		_ = acc.client.Close()
+	}
+	return nil
+}
+
+func (p *Pool) GetName() string {
+	return "gemini"
+}
+
+// IsHealthy reports whether at least one account can serve requests
+func (p *Pool) IsHealthy() bool {
+	for _, acc := range p.accounts {
+		acc.mu.Lock()
+		ejected := acc.ejected
+		acc.mu.Unlock()
+		if !ejected && acc.client.IsHealthy() {
+			return true
+		}
+	}
+	return false
+}
+
+// ListModels lists the models of every account, so the Gems of all accounts are included
+func (p *Pool) ListModels() []providers.ModelInfo {
+	var models []providers.ModelInfo
+	seen := make(map[string]bool)
+	for _, acc := range p.accounts {
+		for _, m := range acc.client.ListModels() {
+			if !seen[m.ID] {
+				seen[m.ID] = true
+				models = append(models, m)
+			}
+		}
+	}
+	return models
+}
+
+// missingGem returns the accounts that do not have the Gem a model ID selects.
+// Nothing is excluded when the model is not a Gem.
+func (p *Pool) missingGem(ctx context.Context, model string) map[int]bool {
+	exclude := make(map[int]bool)
+	if !IsGemModel(model) {
+		return exclude
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(model), "models/"), providers.GemModelPrefix)
+	for _, acc := range p.accounts {
+		if !acc.hasGem(ctx, name) {
+			exclude[acc.id] = true
+		}
+	}
+	return exclude
+}
+
+// pick selects an active account according to the pool strategy, skipping excluded ones
+func (p *Pool) pick(exclude map[int]bool) (*account, error) {
+	var active []*account
+	for _, acc := range p.accounts {
+		acc.mu.Lock()
+		ejected := acc.ejected
+		acc.mu.Unlock()
+		if !ejected && !exclude[acc.id] {
+			active = append(active, acc)
+		}
+	}
+	if len(active) == 0 {
+		return nil, errNoHealthyAccounts
+	}
+
+	// Rotate the starting point so ties are broken round-robin
+	start := int(p.next.Add(1)-1) % len(active)
+	best := active[start]
+	if p.strategy == StrategyRoundRobin {
+		return best, nil
+	}
+
+	for i := 1; i < len(active); i++ {
+		acc := active[(start+i)%len(active)]
+		switch p.strategy {
+		case StrategyLowestLatency:
+			if acc.avgLatency() < best.avgLatency() {
+				best = acc
+			}
+		default:
+			if acc.inflight.Load() < best.inflight.Load() {
+				best = acc
+			}
+		}
+	}
+	return best, nil
+}
+
+// track runs open against an account and records the outcome once the stream ends.
+// Latency is measured to the first chunk so long answers do not penalize an account.
+func (p *Pool) track(ctx context.Context, acc *account, open func() (<-chan providers.StreamChunk, error)) (<-chan providers.StreamChunk, error) {
+	acc.inflight.Add(1)
+	start := time.Now()
+
+	stream, err := open()
+	if err != nil {
+		acc.inflight.Add(-1)
+		p.record(acc, err, 0)
+		return nil, err
+	}
+
+	out := make(chan providers.StreamChunk)
+	go func() {
+		defer close(out)
+		defer acc.inflight.Add(-1)
+
+		var streamErr error
+		var latency time.Duration
+		for chunk := range stream {
+			if latency == 0 {
+				latency = time.Since(start)
+			}
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				p.record(acc, ctx.Err(), 0)
+				return
+			}
+		}
+		p.record(acc, streamErr, latency)
+	}()
+	return out, nil
+}
+
+// record updates an account's health after a request
+func (p *Pool) record(acc *account, err error, latency time.Duration) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	if err == nil {
+		acc.failures = 0
+		if acc.latency == 0 {
+			acc.latency = latency
+		} else {
+			acc.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(acc.latency))
+		}
+		return
+	}
+
+	if !errors.Is(err, providers.ErrAuthExpired) {
+		return
+	}
+
+	acc.failures++
+	if acc.failures >= p.maxAuthFailures && !acc.ejected {
+		acc.ejected = true
+		p.log.Warn("Gemini account ejected after repeated authentication failures",
+			zap.Int("account", acc.id), zap.Int("failures", acc.failures), zap.Error(err))
+	}
+}
+
+// startRecovery periodically re-authenticates ejected accounts
+func (p *Pool) startRecovery() {
+	ticker := time.NewTicker(p.recoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.recoverAccounts()
+		case <-p.stopRecovery:
+			return
+		}
+	}
+}
+
+// startConversationCleanup periodically deletes the conversations the bridge left idle
+// for longer than the conversation TTL
+func (p *Pool) startConversationCleanup() {
+	ticker := time.NewTicker(conversationCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, acc := range p.accounts {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				acc.client.deleteIdleConversations(ctx)
+				cancel()
+			}
+		case <-p.stopRecovery:
+			return
+		}
+	}
+}
+
+func (p *Pool) recoverAccounts() {
+	for _, acc := range p.accounts {
+		acc.mu.Lock()
+		ejected := acc.ejected
+		acc.mu.Unlock()
+		if !ejected {
+			continue
+		}
+
+		if err := acc.client.reauthenticate(acc.client.authGeneration()); err != nil {
+			p.log.Debug("Ejected Gemini account is still failing authentication", zap.Int("account", acc.id), zap.Error(err))
+			continue
+		}
+
+		acc.client.ensureAutoRefresh()
+
+		acc.mu.Lock()
+		acc.ejected = false
+		acc.failures = 0
+		acc.mu.Unlock()
+		p.log.Info("Gemini account re-authenticated and returned to the pool", zap.Int("account", acc.id))
+	}
+}
+
+// AccountStatus describes one pooled account
+type AccountStatus struct {
+	ID       int
+	Ejected  bool
+	InFlight int64
+	CredentialStatus
+}
+
+// Status reports the state of every account
+func (p *Pool) Status() []AccountStatus {
+	statuses := make([]AccountStatus, 0, len(p.accounts))
+	for _, acc := range p.accounts {
+		acc.mu.Lock()
+		ejected := acc.ejected
+		acc.mu.Unlock()
+		statuses = append(statuses, AccountStatus{
+			ID:               acc.id,
+			Ejected:          ejected,
+			InFlight:         acc.inflight.Load(),
+			CredentialStatus: acc.client.Status(),
+		})
+	}
+	return statuses
+}
+
+// UpdateCookies replaces the cookies of an account and returns it to the pool
+func (p *Pool) UpdateCookies(ctx context.Context, id int, creds Credentials) error {
+	acc := p.accountByID(id)
+	if acc == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownAccount, id)
+	}
+	if err := acc.client.UpdateCookies(ctx, creds); err != nil {
+		return err
+	}
+
+	acc.mu.Lock()
+	acc.ejected = false
+	acc.failures = 0
+	acc.mu.Unlock()
+	return nil
+}
+
+// RotateCookies rotates the cookies of an account right away
+func (p *Pool) RotateCookies(id int) error {
+	acc := p.accountByID(id)
+	if acc == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownAccount, id)
+	}
+	return acc.client.RotateCookies()
+}
+
+// ListConversations returns the most recent conversations of an account
+func (p *Pool) ListConversations(ctx context.Context, id int, limit int) ([]Conversation, error) {
+	acc := p.accountByID(id)
+	if acc == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownAccount, id)
+	}
+	return acc.client.ListConversations(ctx, limit)
+}
+
+// ReadConversation returns the turns of one of an account's conversations
+func (p *Pool) ReadConversation(ctx context.Context, id int, conversationID string, limit int) ([]ConversationTurn, error) {
+	acc := p.accountByID(id)
+	if acc == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownAccount, id)
+	}
+	return acc.client.ReadConversation(ctx, conversationID, limit)
+}
+
+// DeleteConversation deletes one of an account's conversations
+func (p *Pool) DeleteConversation(ctx context.Context, id int, conversationID string) error {
+	acc := p.accountByID(id)
+	if acc == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownAccount, id)
+	}
+	return acc.client.DeleteConversation(ctx, conversationID)
+}
+
+func (p *Pool) accountByID(id int) *account {
+	for _, acc := range p.accounts {
+		if acc.id == id {
+			return acc
+		}
+	}
+	return nil
+}
+
+// hasGem reports whether the account has a Gem. Answers are reused for as long as the client
+// reuses its list of Gems: gemsCacheTTL when the Gem was found, gemsRetryInterval when not.
+func (a *account) hasGem(ctx context.Context, name string) bool {
+	a.gemsMu.Lock()
+	defer a.gemsMu.Unlock()
+
+	lookup, ok := a.gems[name]
+	maxAge := gemsRetryInterval
+	if lookup.found {
+		maxAge = gemsCacheTTL
+	}
+	if ok && time.Since(lookup.checked) < maxAge {
+		return lookup.found
+	}
+
+	_, found := a.client.findGem(ctx, name)
+	if a.gems == nil {
+		a.gems = make(map[string]gemLookup)
+	}
+	// Drop expired answers so names asked for once do not pile up
+	for gem, old := range a.gems {
+		if time.Since(old.checked) >= gemsCacheTTL {
+			delete(a.gems, gem)
+		}
+	}
+	a.gems[name] = gemLookup{found: found, checked: time.Now()}
+	return found
+}
+
+func (a *account) avgLatency() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.latency
+}
+
+// accountFromMetadata returns the account ID stored in session metadata, or 0
+func accountFromMetadata(metadata *providers.SessionMetadata) int {
+	switch v := metadata.Extra["account"].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// poolSession is a chat session pinned to one pooled account
+type poolSession struct {
+	providers.ChatSession
+	pool    *Pool
+	account *account
+}
+
+func (s *poolSession) SendMessage(ctx context.Context, message string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := s.SendMessageStream(ctx, message, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+func (s *poolSession) SendMessageStream(ctx context.Context, message string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	return s.pool.track(ctx, s.account, func() (<-chan providers.StreamChunk, error) {
+		return s.ChatSession.SendMessageStream(ctx, message, options...)
+	})
+}
+
+// GetMetadata records the account so a restored session reaches the same conversation
+func (s *poolSession) GetMetadata() *providers.SessionMetadata {
+	metadata := s.ChatSession.GetMetadata()
+	if metadata.Extra == nil {
+		metadata.Extra = map[string]any{}
+	}
+	metadata.Extra["account"] = s.account.id
+	return metadata
+}
+
+// unavailableSession is returned by StartChat when the pool has no accounts; every message fails
+type unavailableSession struct {
+	model string
+	err   error
+}
+
+func (s *unavailableSession) SendMessage(ctx context.Context, message string, options ...providers.GenerateOption) (*providers.Response, error) {
+	return nil, s.err
+}
+
+func (s *unavailableSession) SendMessageStream(ctx context.Context, message string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	return nil, s.err
+}
+
+func (s *unavailableSession) GetMetadata() *providers.SessionMetadata {
+	return &providers.SessionMetadata{Model: s.model}
+}
+
+func (s *unavailableSession) GetHistory() []providers.Message {
+	return nil
+}
+
+func (s *unavailableSession) ChooseCandidate(index int) error {
+	return s.err
+}
+
+func (s *unavailableSession) Clear() {}
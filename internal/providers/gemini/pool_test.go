@@ -0,0 +1,371 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"github.com/imroc/req/v3"
+	"go.uber.org/zap"
+)
+
+// fakeUpstream answers a client's requests offline. The app page carries a session token
+// while signedIn is set, StreamGenerate answers with text and every other call fails.
+type fakeUpstream struct {
+	t    *testing.T
+	text string
+
+	mu       sync.Mutex
+	signedIn bool
+	status   int            // status of StreamGenerate answers, 200 when unset
+	delay    time.Duration  // added before every answer
+	requests map[string]int // by the last element of the URL path
+}
+
+// newTestPool creates a pool over n accounts whose clients talk to fake upstreams.
+// Every account starts signed in; the upstream of account i answers "answer from i".
+func newTestPool(t *testing.T, n int, poolConfig config.GeminiPoolConfig) (*Pool, []*fakeUpstream) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Gemini.Pool = poolConfig
+	for i := range n {
+		cfg.Gemini.Accounts = append(cfg.Gemini.Accounts, config.GeminiAccount{Secure1PSID: fmt.Sprintf("psid-%d", i+1)})
+	}
+	pool := NewPool(cfg, NewMemoryStore(), zap.NewNop())
+
+	var upstreams []*fakeUpstream
+	for _, acc := range pool.accounts {
+		upstream := &fakeUpstream{t: t, text: fmt.Sprintf("answer from %d", acc.id), signedIn: true, requests: make(map[string]int)}
+		upstream.install(acc.client)
+		acc.client.autoRefresh = false
+		signIn(acc.client)
+		upstreams = append(upstreams, upstream)
+	}
+	return pool, upstreams
+}
+
+func (u *fakeUpstream) install(c *Client) {
+	c.httpClient.GetTransport().WrapRoundTripFunc(func(rt http.RoundTripper) req.HttpRoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			status, body := u.answer(path.Base(r.URL.Path))
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    r,
+			}, nil
+		}
+	})
+}
+
+func (u *fakeUpstream) answer(endpoint string) (int, string) {
+	u.mu.Lock()
+	u.requests[endpoint]++
+	signedIn, status, delay := u.signedIn, u.status, u.delay
+	u.mu.Unlock()
+	time.Sleep(delay)
+
+	switch endpoint {
+	case "app":
+		if signedIn {
+			return http.StatusOK, `"SNlM0e":"token"`
+		}
+		return http.StatusOK, "Sign in"
+	case "StreamGenerate":
+		if status != 0 {
+			return status, ""
+		}
+		return http.StatusOK, frameLine(u.t, u.text)
+	case "batchexecute":
+		return http.StatusInternalServerError, ""
+	case "RotateCookies":
+		return http.StatusUnauthorized, ""
+	}
+	return http.StatusOK, ""
+}
+
+func (u *fakeUpstream) count(endpoint string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.requests[endpoint]
+}
+
+// signIn gives a client a session token without reaching upstream
+func signIn(c *Client) {
+	c.mu.Lock()
+	c.at = "token"
+	c.healthy = true
+	c.mu.Unlock()
+}
+
+// signOut drops a client's session and records a failed sign-in, so requests fail with
+// ErrAuthExpired until the re-authentication cooldown is over
+func signOut(c *Client) {
+	c.mu.Lock()
+	c.at = ""
+	c.mu.Unlock()
+	c.authMu.Lock()
+	c.lastAuthErr = fmt.Errorf("%w: signed out", providers.ErrAuthExpired)
+	c.lastAuthAttempt = time.Now()
+	c.authMu.Unlock()
+}
+
+// setGems sets the Gems a client has listed and when
+func setGems(c *Client, fetched time.Time, gems ...Gem) {
+	c.gemsMu.Lock()
+	c.gems = gems
+	c.gemsFetched = fetched
+	c.gemsMu.Unlock()
+}
+
+func generateText(t *testing.T, p *Pool, options ...providers.GenerateOption) string {
+	t.Helper()
+	response, err := p.GenerateContent(context.Background(), "hello", options...)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	return response.Text
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		inflight []int64
+		latency  []time.Duration
+		ejected  []bool
+		exclude  map[int]bool
+		want     []int // accounts picked by consecutive calls
+	}{
+		{
+			name:     "round robin",
+			strategy: StrategyRoundRobin,
+			inflight: []int64{5, 0, 0},
+			want:     []int{1, 2, 3, 1},
+		},
+		{
+			name:     "least busy",
+			strategy: StrategyLeastBusy,
+			inflight: []int64{2, 0, 1},
+			want:     []int{2, 2, 2},
+		},
+		{
+			name:     "least busy breaks ties round robin",
+			strategy: StrategyLeastBusy,
+			inflight: []int64{0, 0, 1},
+			want:     []int{1, 2, 1, 1},
+		},
+		{
+			name:     "lowest latency",
+			strategy: StrategyLowestLatency,
+			latency:  []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+			want:     []int{2, 2},
+		},
+		{
+			name:     "ejected and excluded accounts are skipped",
+			strategy: StrategyRoundRobin,
+			ejected:  []bool{true, false, false},
+			exclude:  map[int]bool{3: true},
+			want:     []int{2, 2},
+		},
+		{
+			name:     "nothing to pick",
+			strategy: StrategyLeastBusy,
+			ejected:  []bool{true, false, false},
+			exclude:  map[int]bool{2: true, 3: true},
+			want:     []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pool{strategy: tt.strategy}
+			for i := range 3 {
+				acc := &account{id: i + 1}
+				if tt.inflight != nil {
+					acc.inflight.Store(tt.inflight[i])
+				}
+				if tt.latency != nil {
+					acc.latency = tt.latency[i]
+				}
+				if tt.ejected != nil {
+					acc.ejected = tt.ejected[i]
+				}
+				p.accounts = append(p.accounts, acc)
+			}
+
+			var picked []int
+			for range tt.want {
+				acc, err := p.pick(tt.exclude)
+				if err != nil {
+					if !errors.Is(err, providers.ErrUpstreamUnavailable) {
+						t.Errorf("error %v, want ErrUpstreamUnavailable", err)
+					}
+					picked = append(picked, 0)
+					continue
+				}
+				picked = append(picked, acc.id)
+			}
+			if fmt.Sprint(picked) != fmt.Sprint(tt.want) {
+				t.Errorf("picked %v, want %v", picked, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	p := &Pool{maxAuthFailures: 2, log: zap.NewNop()}
+	acc := &account{id: 1}
+
+	p.record(acc, providers.ErrRateLimited, 0)
+	p.record(acc, providers.ErrAuthExpired, 0)
+	if acc.failures != 1 || acc.ejected {
+		t.Fatalf("after one auth failure: %d failures, ejected %v", acc.failures, acc.ejected)
+	}
+	// A success in between starts the count over
+	p.record(acc, nil, 100*time.Millisecond)
+	p.record(acc, providers.ErrAuthExpired, 0)
+	if acc.failures != 1 || acc.ejected {
+		t.Fatalf("after a success: %d failures, ejected %v", acc.failures, acc.ejected)
+	}
+	p.record(acc, fmt.Errorf("wrapped: %w", providers.ErrAuthExpired), 0)
+	if !acc.ejected {
+		t.Errorf("not ejected after %d consecutive auth failures", acc.failures)
+	}
+
+	p.record(acc, nil, 200*time.Millisecond)
+	if want := 130 * time.Millisecond; acc.latency != want {
+		t.Errorf("average latency %v, want %v", acc.latency, want)
+	}
+}
+
+func TestGenerateEjectsFailingAccounts(t *testing.T) {
+	pool, upstreams := newTestPool(t, 2, config.GeminiPoolConfig{Strategy: StrategyRoundRobin, MaxAuthFailures: 2})
+	signOut(pool.accounts[0].client)
+
+	// Account 1 is tried first by every other request until it fails twice
+	for i := range 4 {
+		if got := generateText(t, pool); got != "answer from 2" {
+			t.Errorf("request %d answered %q", i+1, got)
+		}
+	}
+	status := pool.Status()
+	if !status[0].Ejected || status[1].Ejected {
+		t.Errorf("ejected: %v, %v; want only account 1", status[0].Ejected, status[1].Ejected)
+	}
+	if failures := pool.accounts[0].failures; failures != 2 {
+		t.Errorf("account 1 failed %d times, want 2 before it was ejected", failures)
+	}
+	if got := upstreams[1].count("StreamGenerate"); got != 4 {
+		t.Errorf("account 2 served %d requests, want 4", got)
+	}
+
+	// Other errors are returned without trying another account
+	upstreams[1].status = http.StatusInternalServerError
+	if _, err := pool.GenerateContent(context.Background(), "hello"); !errors.Is(err, providers.ErrUpstreamUnavailable) {
+		t.Errorf("error %v, want ErrUpstreamUnavailable", err)
+	}
+
+	// Every account ejected
+	pool.accounts[1].ejected = true
+	if _, err := pool.GenerateContent(context.Background(), "hello"); !errors.Is(err, errNoHealthyAccounts) {
+		t.Errorf("error %v, want errNoHealthyAccounts", err)
+	}
+}
+
+func TestRecoverAccounts(t *testing.T) {
+	pool, upstreams := newTestPool(t, 1, config.GeminiPoolConfig{})
+	acc := pool.accounts[0]
+	acc.ejected = true
+	acc.failures = 3
+	upstreams[0].signedIn = false
+
+	pool.recoverAccounts()
+	if !acc.ejected {
+		t.Fatal("account returned to the pool while its sign-in fails")
+	}
+
+	// The next attempt, after the re-authentication cooldown, succeeds
+	upstreams[0].signedIn = true
+	acc.client.lastAuthAttempt = time.Now().Add(-reauthCooldown)
+	pool.recoverAccounts()
+	if acc.ejected || acc.failures != 0 {
+		t.Fatalf("after recovery: ejected %v with %d failures", acc.ejected, acc.failures)
+	}
+	if !pool.IsHealthy() {
+		t.Error("pool unhealthy after its account recovered")
+	}
+	if got := generateText(t, pool); got != "answer from 1" {
+		t.Errorf("answered %q", got)
+	}
+}
+
+func TestGemRouting(t *testing.T) {
+	pool, upstreams := newTestPool(t, 2, config.GeminiPoolConfig{Strategy: StrategyRoundRobin})
+	setGems(pool.accounts[0].client, time.Now(), Gem{ID: "abc123", Name: "Coding partner"})
+	setGems(pool.accounts[1].client, time.Now())
+
+	for range 3 {
+		if got := generateText(t, pool, providers.WithModel("gem/coding-partner")); got != "answer from 1" {
+			t.Errorf("Gem request answered %q", got)
+		}
+	}
+	if got := upstreams[1].count("StreamGenerate"); got != 0 {
+		t.Errorf("account 2 got %d Gem requests", got)
+	}
+
+	// Requests that are not for a Gem still go to every account
+	if got := generateText(t, pool); got != "answer from 2" {
+		t.Errorf("model request answered %q", got)
+	}
+
+	_, err := pool.GenerateContent(context.Background(), "hello", providers.WithModel("gem/coding-partner"), providers.WithAccount(2))
+	if !errors.Is(err, providers.ErrUnknownModel) {
+		t.Errorf("Gem request pinned to an account without it: error %v, want ErrUnknownModel", err)
+	}
+	_, err = pool.GenerateContent(context.Background(), "hello", providers.WithModel("gem/unknown"))
+	if !errors.Is(err, providers.ErrUnknownModel) {
+		t.Errorf("unknown Gem: error %v, want ErrUnknownModel", err)
+	}
+}
+
+func TestGemLookupsAreCachedPerAccount(t *testing.T) {
+	pool, upstreams := newTestPool(t, 2, config.GeminiPoolConfig{})
+	setGems(pool.accounts[0].client, time.Now(), Gem{ID: "abc123", Name: "Coding partner"})
+	// Account 2 has never listed its Gems, and listing them is slow
+	upstreams[1].delay = 20 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if exclude := pool.missingGem(context.Background(), "gem/coding-partner"); !exclude[2] || exclude[1] {
+				t.Errorf("excluded %v, want account 2", exclude)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := upstreams[1].count("batchexecute"); got != 1 {
+		t.Errorf("account 2 listed its Gems %d times for concurrent requests, want once", got)
+	}
+
+	// The answer outlives the client's own retry throttle
+	setGems(pool.accounts[1].client, time.Time{})
+	pool.missingGem(context.Background(), "gem/coding-partner")
+	if got := upstreams[1].count("batchexecute"); got != 1 {
+		t.Errorf("account 2 listed its Gems %d times, want the cached answer", got)
+	}
+	if got := upstreams[0].count("batchexecute"); got != 0 {
+		t.Errorf("account 1 listed its Gems %d times, want its fresh list used", got)
+	}
+}
@@ -0,0 +1,104 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// retryBaseDelay is the delay before retrying a request after re-authenticating.
+	// Each delay is jittered so requests that failed together do not retry together.
+	retryBaseDelay = 500 * time.Millisecond
+
+	// reauthCooldown is how long a failed re-authentication is reported to later
+	// callers before another attempt is made
+	reauthCooldown = 30 * time.Second
+)
+
+// withReauth runs open and, if upstream rejects the credentials, re-authenticates the
+// client once and retries after a jittered backoff
+func (c *Client) withReauth(ctx context.Context, open func() (<-chan providers.StreamChunk, error)) (<-chan providers.StreamChunk, error) {
+	return reauthRetry(ctx, c, open)
+}
+
+// reauthRetry is withReauth for calls returning any result
+func reauthRetry[T any](ctx context.Context, c *Client, open func() (T, error)) (T, error) {
+	generation := c.authGeneration()
+	result, err := open()
+	if err == nil || !errors.Is(err, providers.ErrAuthExpired) {
+		return result, err
+	}
+
+	var zero T
+	c.log.Info("Gemini rejected the session, re-authenticating", zap.Error(err))
+	if authErr := c.reauthenticate(generation); authErr != nil {
+		return zero, fmt.Errorf("%w (re-authentication failed: %v)", err, authErr)
+	}
+
+	if err := sleepJittered(ctx, retryBaseDelay); err != nil {
+		return zero, err
+	}
+	return open()
+}
+
+// reauthenticate refreshes the session token, rotating cookies if that fails. Only one
+// refresh runs at a time; callers that saw a failure from an older token generation
+// reuse the refresh that already happened instead of starting another one.
+func (c *Client) reauthenticate(failedGeneration uint64) error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.authGeneration() != failedGeneration {
+		return nil
+	}
+	if c.lastAuthErr != nil && time.Since(c.lastAuthAttempt) < reauthCooldown {
+		return c.lastAuthErr
+	}
+
+	c.setHealthy(false)
+	c.lastAuthAttempt = time.Now()
+	if err := c.authenticate(); err != nil {
+		c.lastAuthErr = err
+		c.log.Warn("Gemini re-authentication failed", zap.Error(err))
+		return err
+	}
+	c.lastAuthErr = nil
+
+	_ = c.SaveCachedCookies()
+	c.log.Info("Gemini session re-authenticated")
+	return nil
+}
+
+// authGeneration returns a counter that changes every time a new session token is fetched
+func (c *Client) authGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authGen
+}
+
+func (c *Client) setHealthy(healthy bool) {
+	c.mu.Lock()
+	c.healthy = healthy
+	c.mu.Unlock()
+}
+
+// sleepJittered waits between half and one and a half times base, or until ctx is done
+func sleepJittered(ctx context.Context, base time.Duration) error {
+	delay := base/2 + time.Duration(rand.Int64N(int64(base)))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,7 +2,6 @@ package gemini
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"ai-bridges/internal/providers"
@@ -10,55 +9,75 @@ import (
 
 // ChatSession implements providers.ChatSession for Gemini
 type ChatSession struct {
-	client   *Client
-	model    string
-	metadata *providers.SessionMetadata
-	history  []providers.Message
+	client     *Client
+	model      string
+	metadata   *providers.SessionMetadata
+	history    []providers.Message
+	candidates []providers.Candidate // candidates of the last response
 }
 
 // SendMessage sends a message in the chat session
 func (s *ChatSession) SendMessage(ctx context.Context, message string, options ...providers.GenerateOption) (*providers.Response, error) {
-	s.client.reqMu.Lock()
-	defer s.client.reqMu.Unlock()
-	
-	if s.client.at == "" {
-		return nil, fmt.Errorf("client not initialized")
-	}
-
-	// Build conversation context
-	inner := []interface{}{
-		[]interface{}{message},
-		nil,
-		s.buildMetadata(),
+	stream, err := s.send(ctx, message, false, options)
+	if err != nil {
+		return nil, err
 	}
+	return providers.CollectStream(stream)
+}
 
-	innerJSON, _ := json.Marshal(inner)
-	outer := []interface{}{nil, string(innerJSON)}
-	outerJSON, _ := json.Marshal(outer)
+// SendMessageStream sends a message in the chat session and streams the reply.
+// Session metadata and history are updated once the final response arrives.
+func (s *ChatSession) SendMessageStream(ctx context.Context, message string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	return s.send(ctx, message, true, options)
+}
 
-	formData := map[string]string{
-		"at":    s.client.at,
-		"f.req": string(outerJSON),
+// send sends a message in the conversation; live is set when the caller streams the deltas
+func (s *ChatSession) send(ctx context.Context, message string, live bool, options []providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	config := &providers.GenerateConfig{
+		Model: s.model,
+	}
+	for _, opt := range options {
+		opt(config)
 	}
 
-	resp, err := s.client.httpClient.R().
-		SetFormData(formData).
-		SetQueryParam("at", s.client.at).
-		Post(EndpointGenerate)
-
+	model, err := s.client.resolveModel(ctx, config.Model)
 	if err != nil {
 		return nil, err
 	}
+	config.Locale = s.client.locale(config.Locale)
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("chat failed with status: %d", resp.StatusCode)
-	}
+	chunks, err := s.client.withReauth(ctx, func() (<-chan providers.StreamChunk, error) {
+		promptPart, err := s.client.buildPromptPart(ctx, message, config.Files)
+		if err != nil {
+			return nil, err
+		}
 
-	response, err := s.client.parseResponse(resp.String())
+		return s.client.streamGenerate(ctx, model, generatePayload(promptPart, s.buildMetadata(), model, config.Locale), config, live)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	out := make(chan providers.StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			if chunk.Response != nil {
+				s.recordTurn(message, chunk.Response)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// recordTurn updates session metadata and history from a completed response
+func (s *ChatSession) recordTurn(message string, response *providers.Response) {
 	// Update session metadata
 	if response.Metadata != nil {
 		if cid, ok := response.Metadata["cid"].(string); ok && cid != "" {
@@ -81,6 +100,8 @@ func (s *ChatSession) SendMessage(ctx context.Context, message string, options .
 		}
 	}
 
+	s.candidates = response.Candidates
+
 	// Update history
 	s.history = append(s.history, providers.Message{
 		Role:    "user",
@@ -89,9 +110,28 @@ func (s *ChatSession) SendMessage(ctx context.Context, message string, options .
 	s.history = append(s.history, providers.Message{
 		Role:    "model",
 		Content: response.Text,
+		Images:  response.Images,
 	})
+}
 
-	return response, nil
+// ChooseCandidate makes the next message continue from another candidate of the last response
+func (s *ChatSession) ChooseCandidate(index int) error {
+	if index < 0 || index >= len(s.candidates) {
+		return fmt.Errorf("candidate index %d out of range (%d candidates)", index, len(s.candidates))
+	}
+
+	candidate := s.candidates[index]
+	if s.metadata == nil {
+		s.metadata = &providers.SessionMetadata{}
+	}
+	s.metadata.ChoiceID = candidate.ID
+
+	// The last history entry is the model reply the candidate replaces
+	if n := len(s.history); n > 0 && s.history[n-1].Role == "model" {
+		s.history[n-1].Content = candidate.Content
+		s.history[n-1].Images = candidate.Images
+	}
+	return nil
 }
 
 // GetMetadata returns session metadata
@@ -114,6 +154,7 @@ func (s *ChatSession) GetHistory() []providers.Message {
 func (s *ChatSession) Clear() {
 	s.history = []providers.Message{}
 	s.metadata = nil
+	s.candidates = nil
 }
 
 // buildMetadata builds metadata array for API request
@@ -0,0 +1,222 @@
+package gemini
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+// streamGenerate posts a StreamGenerate request for the given model and parses the response
+// frames as they arrive. live is set when the caller forwards the deltas instead of only
+// collecting the final response.
+//
+// reqMu is held until the stream has been fully read or ctx is done: the reading goroutine
+// stops as soon as ctx is cancelled, even if nobody reads the channel any more. Callers that
+// stop reading early must therefore cancel ctx, or the account stays blocked for later requests.
+func (c *Client) streamGenerate(ctx context.Context, model Model, inner []interface{}, config *providers.GenerateConfig, live bool) (<-chan providers.StreamChunk, error) {
+	c.reqMu.Lock()
+
+	c.mu.RLock()
+	at := c.at
+	c.mu.RUnlock()
+	if at == "" {
+		c.reqMu.Unlock()
+		return nil, fmt.Errorf("%w: client not initialized", providers.ErrAuthExpired)
+	}
+
+	innerJSON, _ := json.Marshal(inner)
+	outer := []interface{}{nil, string(innerJSON)}
+	outerJSON, _ := json.Marshal(outer)
+
+	formData := map[string]string{
+		"at":    at,
+		"f.req": string(outerJSON),
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		DisableAutoReadResponse().
+		SetHeaders(DefaultHeaders).
+		SetHeaders(model.Headers()).
+		SetHeader("Accept-Language", acceptLanguage(config.Locale)).
+		SetFormData(formData).
+		SetQueryParam("at", at).
+		SetQueryParam("hl", config.Locale).
+		Post(EndpointGenerate)
+
+	if err != nil {
+		c.reqMu.Unlock()
+		return nil, providers.RequestError("generate", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		c.reqMu.Unlock()
+		return nil, statusError("generate", resp.StatusCode)
+	}
+
+	chunks := make(chan providers.StreamChunk)
+	go func() {
+		defer c.reqMu.Unlock()
+		defer resp.Body.Close()
+		defer close(chunks)
+
+		final, rewritten := readFrames(ctx, resp.Body, chunks, config.IncludeThoughts)
+		if final == nil {
+			return
+		}
+		if rewritten && live {
+			c.log.Warn("Gemini rewrote text that was already streamed; the streamed answer differs from the final one",
+				zap.String("conversation_id", final.ConversationID))
+		}
+		c.trackConversation(final.ConversationID)
+	}()
+
+	return chunks, nil
+}
+
+// generatePayload builds the inner f.req payload of a StreamGenerate request. metadata
+// continues a conversation and is nil for a new one.
+func generatePayload(promptPart, metadata []interface{}, model Model, locale string) []interface{} {
+	inner := []interface{}{promptPart, []interface{}{locale}, metadata}
+	if model.GemID != "" {
+		// The Gem ID sits at index 19; the fields in between stay empty
+		inner = append(inner, make([]interface{}, 16)...)
+		inner = append(inner, model.GemID)
+	}
+	return inner
+}
+
+// readFrames reads length-prefixed frames from the response body and emits the text (and,
+// if requested, the thoughts) that each frame adds over the previous one. The last parsed
+// frame is sent as the final response and returned, or nil if the stream failed. rewritten
+// reports that the final text does not extend what was streamed, so the deltas are incomplete.
+func readFrames(ctx context.Context, body io.Reader, chunks chan<- providers.StreamChunk, includeThoughts bool) (final *providers.Response, rewritten bool) {
+	send := func(chunk providers.StreamChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	reader := bufio.NewReader(body)
+	var last *providers.Response
+	var upstreamErr error
+	emitted, emittedThoughts := "", ""
+
+	for {
+		line, err := reader.ReadString('\n')
+		if frame, ok := parseFrame(line); ok {
+			if !includeThoughts {
+				stripThoughts(frame)
+			}
+
+			// Frames are cumulative. If upstream rewrites earlier text we cannot take it back,
+			// so we wait until the text extends what was already sent. The start of an image
+			// placeholder is held back until the frame that completes (and strips) it.
+			var chunk providers.StreamChunk
+			if delta, ok := cumulativeDelta(frame.Thoughts, emittedThoughts); ok {
+				chunk.Thought = delta
+				emittedThoughts = frame.Thoughts
+			}
+			text := withoutPendingPlaceholder(frame.Text)
+			if delta, ok := cumulativeDelta(text, emitted); ok {
+				chunk.Text = delta
+				emitted = text
+			}
+			if chunk.Text != "" || chunk.Thought != "" {
+				if !send(chunk) {
+					return nil, false
+				}
+			}
+			last = frame
+		} else if code, ok := parseErrorCode(line); ok {
+			upstreamErr = frameError(code)
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				// A read aborted by the request deadline is a timeout, not a broken upstream
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				send(providers.StreamChunk{Err: providers.RequestError("read response stream", err)})
+				return nil, false
+			}
+			break
+		}
+	}
+
+	if last == nil {
+		if upstreamErr == nil {
+			upstreamErr = fmt.Errorf("%w: no answer in the Gemini response", providers.ErrParseFailure)
+		}
+		send(providers.StreamChunk{Err: upstreamErr})
+		return nil, false
+	}
+
+	if last.Text == "" && len(last.Images) == 0 {
+		send(providers.StreamChunk{Err: errEmptyAnswer})
+		return nil, false
+	}
+
+	// Send what the last frame holds beyond the streamed text, so clients that only read the
+	// deltas get the whole answer. Rewritten text cannot be taken back; the final response
+	// still carries the whole answer.
+	rewritten = !strings.HasPrefix(last.Text, emitted) || !strings.HasPrefix(last.Thoughts, emittedThoughts)
+	if !rewritten {
+		tail := providers.StreamChunk{
+			Text:    last.Text[len(emitted):],
+			Thought: last.Thoughts[len(emittedThoughts):],
+		}
+		if tail.Text != "" || tail.Thought != "" {
+			if !send(tail) {
+				return nil, false
+			}
+		}
+	}
+
+	send(providers.StreamChunk{Response: last})
+	return last, rewritten
+}
+
+// cumulativeDelta returns the part of current that extends what was already emitted
+func cumulativeDelta(current, emitted string) (string, bool) {
+	if len(current) <= len(emitted) || !strings.HasPrefix(current, emitted) {
+		return "", false
+	}
+	return current[len(emitted):], true
+}
+
+// withoutPendingPlaceholder cuts off the start of an image placeholder at the end of text.
+// parseFrame only strips complete placeholders, so a partial one must not be streamed.
+func withoutPendingPlaceholder(text string) string {
+	if i := strings.LastIndex(text, imagePlaceholderPrefix); i >= 0 && partialPlaceholderRe.MatchString(text[i:]) {
+		return text[:i]
+	}
+	for n := min(len(imagePlaceholderPrefix)-1, len(text)); n > 0; n-- {
+		if strings.HasSuffix(text, imagePlaceholderPrefix[:n]) {
+			return text[:len(text)-n]
+		}
+	}
+	return text
+}
+
+// stripThoughts removes reasoning from a response when the caller did not ask for it
+func stripThoughts(response *providers.Response) {
+	response.Thoughts = ""
+	for i := range response.Candidates {
+		response.Candidates[i].Thoughts = ""
+	}
+}
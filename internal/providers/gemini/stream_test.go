@@ -0,0 +1,173 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-bridges/internal/providers"
+)
+
+// frameLine builds a StreamGenerate response line whose first candidate holds text
+func frameLine(t *testing.T, text string) string {
+	t.Helper()
+	payload, err := json.Marshal([]interface{}{nil, []interface{}{"c_1", "r_1"}, nil, nil, []interface{}{
+		[]interface{}{"rc_1", []interface{}{text}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := json.Marshal([]interface{}{[]interface{}{"wrb.fr", nil, string(payload)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line) + "\n"
+}
+
+// frameStream runs readFrames over the given lines
+func frameStream(t *testing.T, lines ...string) <-chan providers.StreamChunk {
+	t.Helper()
+	chunks := make(chan providers.StreamChunk)
+	go func() {
+		defer close(chunks)
+		readFrames(context.Background(), strings.NewReader(strings.Join(lines, "")), chunks, false)
+	}()
+	return chunks
+}
+
+// collectFrames runs readFrames over the given lines and returns the streamed text, the final
+// response and the stream error
+func collectFrames(t *testing.T, lines ...string) (string, *providers.Response, error) {
+	t.Helper()
+	chunks := frameStream(t, lines...)
+
+	var streamed strings.Builder
+	var final *providers.Response
+	var err error
+	for chunk := range chunks {
+		streamed.WriteString(chunk.Text)
+		if chunk.Response != nil {
+			final = chunk.Response
+		}
+		if chunk.Err != nil {
+			err = chunk.Err
+		}
+	}
+	return streamed.String(), final, err
+}
+
+func TestReadFramesStreamsDeltas(t *testing.T) {
+	streamed, final, err := collectFrames(t,
+		frameLine(t, "Hello"),
+		frameLine(t, "Hello, wor"),
+		frameLine(t, "Hello, world!"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamed != "Hello, world!" {
+		t.Errorf("streamed %q, want %q", streamed, "Hello, world!")
+	}
+	if final == nil || final.Text != "Hello, world!" {
+		t.Errorf("final response %+v, want the full text", final)
+	}
+}
+
+func TestReadFramesHoldsBackPartialPlaceholder(t *testing.T) {
+	streamed, final, err := collectFrames(t,
+		frameLine(t, "Here is a cat: http://googleusercon"),
+		frameLine(t, "Here is a cat: http://googleusercontent.com/image_generation_content/0\n"),
+		frameLine(t, "Here is a cat: http://googleusercontent.com/image_generation_content/0\nEnjoy."),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Here is a cat: Enjoy."
+	if streamed != want {
+		t.Errorf("streamed %q, want %q", streamed, want)
+	}
+	if final == nil || final.Text != want {
+		t.Errorf("final response %+v, want text %q", final, want)
+	}
+}
+
+func TestReadFramesSendsTailAfterPlaceholderAtEnd(t *testing.T) {
+	streamed, _, err := collectFrames(t,
+		frameLine(t, "Done h"),
+		frameLine(t, "Done here"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if streamed != "Done here" {
+		t.Errorf("streamed %q, want %q", streamed, "Done here")
+	}
+}
+
+func TestReadFramesKeepsFinalResponseWhenStreamedTextIsRewritten(t *testing.T) {
+	lines := []string{
+		frameLine(t, "The answer is 4"),
+		frameLine(t, "Actually, the answer is 5"),
+	}
+	streamed, final, err := collectFrames(t, lines...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The rewrite cannot be streamed, but the final response holds the whole answer
+	if streamed != "The answer is 4" {
+		t.Errorf("streamed %q, want %q", streamed, "The answer is 4")
+	}
+	if final == nil || final.Text != "Actually, the answer is 5" {
+		t.Errorf("final response %+v, want the rewritten text", final)
+	}
+
+	collected, err := providers.CollectStream(frameStream(t, lines...))
+	if err != nil || collected.Text != "Actually, the answer is 5" {
+		t.Errorf("collected %+v, %v, want the rewritten text", collected, err)
+	}
+}
+
+func TestReadFramesClassifiesEmptyAnswerAsBlocked(t *testing.T) {
+	payload, _ := json.Marshal([]interface{}{nil, []interface{}{"c_1", "r_1"}, nil, nil, []interface{}{
+		[]interface{}{"rc_1", nil},
+	}})
+	line, _ := json.Marshal([]interface{}{[]interface{}{"wrb.fr", nil, string(payload)}})
+
+	for name, lines := range map[string][]string{
+		"no text":    {string(line) + "\n"},
+		"empty text": {frameLine(t, "")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, final, err := collectFrames(t, lines...)
+			if !errors.Is(err, providers.ErrContentBlocked) {
+				t.Errorf("error %v, want ErrContentBlocked", err)
+			}
+			if final != nil {
+				t.Errorf("unexpected final response %+v", final)
+			}
+		})
+	}
+}
+
+func TestReadFramesStopsWhenCancelledWithoutReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks := make(chan providers.StreamChunk)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readFrames(ctx, strings.NewReader(frameLine(t, "Hello")+frameLine(t, "Hello, world")), chunks, false)
+	}()
+
+	// Read one chunk, then walk away and cancel like a handler whose client disconnected
+	<-chunks
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("readFrames still blocked after the context was cancelled")
+	}
+}
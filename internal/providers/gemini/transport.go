@@ -0,0 +1,101 @@
+package gemini
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ai-bridges/internal/config"
+
+	"github.com/imroc/req/v3"
+)
+
+// googleURL is where account cookies are stored in the jar; they apply to all Google subdomains
+var googleURL = &url.URL{Scheme: "https", Host: "google.com", Path: "/"}
+
+// BrowserProfile describes the browser an account's traffic looks like: its User-Agent,
+// client hint headers and TLS fingerprint
+type BrowserProfile struct {
+	Name        string
+	UserAgent   string
+	ClientHints map[string]string
+	fingerprint func(*req.Client) *req.Client
+}
+
+const defaultBrowserProfile = "chrome"
+
+// browserProfiles lists the profiles that can be selected with GEMINI_BROWSER_PROFILE
+var browserProfiles = map[string]BrowserProfile{
+	"chrome": {
+		Name:      "chrome",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		ClientHints: map[string]string{
+			"Sec-Ch-Ua":          `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+			"Sec-Ch-Ua-Mobile":   "?0",
+			"Sec-Ch-Ua-Platform": `"Windows"`,
+		},
+		fingerprint: (*req.Client).SetTLSFingerprintChrome,
+	},
+	"edge": {
+		Name:      "edge",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+		ClientHints: map[string]string{
+			"Sec-Ch-Ua":          `"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`,
+			"Sec-Ch-Ua-Mobile":   "?0",
+			"Sec-Ch-Ua-Platform": `"Windows"`,
+		},
+		fingerprint: (*req.Client).SetTLSFingerprintEdge,
+	},
+	"firefox": {
+		Name:        "firefox",
+		UserAgent:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+		fingerprint: (*req.Client).SetTLSFingerprintFirefox,
+	},
+	"safari": {
+		Name:        "safari",
+		UserAgent:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+		fingerprint: (*req.Client).SetTLSFingerprintSafari,
+	},
+}
+
+// resolveBrowserProfile returns the account's browser profile with its User-Agent override applied
+func resolveBrowserProfile(account config.GeminiAccount) BrowserProfile {
+	profile, ok := browserProfiles[account.BrowserProfile]
+	if !ok {
+		profile = browserProfiles[defaultBrowserProfile]
+	}
+	if account.UserAgent != "" {
+		profile.UserAgent = account.UserAgent
+	}
+	return profile
+}
+
+// newTransport builds the single HTTP client an account uses for every upstream call:
+// page loads, cookie rotation, generation and uploads. They all share one cookie jar,
+// one proxy and one browser profile, so the traffic looks like a single browser.
+func newTransport(profile BrowserProfile, proxy *url.URL, jar *cookieJar, locale string) *req.Client {
+	client := req.NewClient().
+		SetTimeout(2*time.Minute).
+		SetCookieJar(jar).
+		SetUserAgent(profile.UserAgent).
+		SetCommonHeader("Accept-Language", acceptLanguage(locale)).
+		SetCommonHeaders(profile.ClientHints)
+	if profile.fingerprint != nil {
+		profile.fingerprint(client)
+	}
+	if proxy != nil {
+		client.SetProxy(http.ProxyURL(proxy))
+	}
+	return client
+}
+
+// acceptLanguage builds the Accept-Language header a browser set to locale sends,
+// e.g. "pt-BR,pt;q=0.9" for pt-BR
+func acceptLanguage(locale string) string {
+	base, _, ok := strings.Cut(locale, "-")
+	if !ok {
+		return locale
+	}
+	return locale + "," + base + ";q=0.9"
+}
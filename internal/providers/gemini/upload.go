@@ -0,0 +1,52 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// uploadFile uploads a file to Gemini's content push service and returns the
+// identifier used to reference it in the generate payload
+func (c *Client) uploadFile(ctx context.Context, file providers.File) (string, error) {
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetHeaders(UploadHeaders).
+		SetFileBytes("file", file.Name, file.Data).
+		Post(c.uploadURL)
+	if err != nil {
+		return "", providers.RequestError("upload "+file.Name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("upload of "+file.Name, resp.StatusCode)
+	}
+
+	id := strings.TrimSpace(resp.String())
+	if id == "" {
+		return "", fmt.Errorf("%w: upload of %s returned no file identifier", providers.ErrParseFailure, file.Name)
+	}
+	return id, nil
+}
+
+// buildPromptPart uploads any attached files and returns the prompt element of the
+// f.req payload, which references the uploaded files by identifier and name
+func (c *Client) buildPromptPart(ctx context.Context, prompt string, files []providers.File) ([]interface{}, error) {
+	if len(files) == 0 {
+		return []interface{}{prompt}, nil
+	}
+
+	refs := make([]interface{}, 0, len(files))
+	for _, file := range files {
+		id, err := c.uploadFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, []interface{}{[]interface{}{id}, file.Name})
+	}
+
+	return []interface{}{prompt, 0, nil, refs}, nil
+}
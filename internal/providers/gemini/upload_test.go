@@ -0,0 +1,115 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+// newUploadTestClient starts a stand-in upload service serving handler and returns a client
+// that uploads to it
+func newUploadTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	account := config.GeminiAccount{Secure1PSID: "psid", Secure1PSIDTS: "psidts"}
+	return NewClient(account, &config.Config{}, NewMemoryStore(), zap.NewNop(), WithUploadURL(server.URL+"/upload"))
+}
+
+func TestBuildPromptPartUploadsFiles(t *testing.T) {
+	uploads := 0
+	c := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/upload" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Push-ID"); got != UploadHeaders["Push-ID"] {
+			t.Errorf("Push-ID = %q", got)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("no file in the multipart upload: %v", err)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+
+		uploads++
+		switch header.Filename {
+		case "notes.txt":
+			if string(data) != "some notes" {
+				t.Errorf("uploaded %q for notes.txt", data)
+			}
+			io.WriteString(w, "/contrib_service/ttl_1d/notes-id\n")
+		case "cat.png":
+			io.WriteString(w, "/contrib_service/ttl_1d/cat-id")
+		default:
+			t.Errorf("unexpected file %q", header.Filename)
+		}
+	})
+
+	files := []providers.File{
+		{Name: "notes.txt", MIMEType: "text/plain", Data: []byte("some notes")},
+		{Name: "cat.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+	}
+	promptPart, err := c.buildPromptPart(context.Background(), "Summarize these", files)
+	if err != nil {
+		t.Fatalf("buildPromptPart: %v", err)
+	}
+	if uploads != 2 {
+		t.Errorf("got %d uploads, want 2", uploads)
+	}
+
+	inner, err := json.Marshal(generatePayload(promptPart, nil, Model{}, "en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[["Summarize these",0,null,[[["/contrib_service/ttl_1d/notes-id"],"notes.txt"],[["/contrib_service/ttl_1d/cat-id"],"cat.png"]]],["en"],null]`
+	if string(inner) != want {
+		t.Errorf("f.req payload\n got %s\nwant %s", inner, want)
+	}
+}
+
+func TestBuildPromptPartWithoutFiles(t *testing.T) {
+	c := newUploadTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upload %s", r.URL.Path)
+	})
+
+	promptPart, err := c.buildPromptPart(context.Background(), "Hello", nil)
+	if err != nil {
+		t.Fatalf("buildPromptPart: %v", err)
+	}
+	inner, _ := json.Marshal(generatePayload(promptPart, nil, Model{}, "en"))
+	if want := `[["Hello"],["en"],null]`; string(inner) != want {
+		t.Errorf("f.req payload %s, want %s", inner, want)
+	}
+}
+
+func TestUploadFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    error
+	}{
+		{"rejected", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) }, providers.ErrAuthExpired},
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, providers.ErrUpstreamUnavailable},
+		{"no identifier", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "  \n") }, providers.ErrParseFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newUploadTestClient(t, tt.handler)
+			_, err := c.buildPromptPart(context.Background(), "Describe", []providers.File{{Name: "cat.png", MIMEType: "image/png", Data: []byte("png")}})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package geminiapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// finishBlocked lists the finish reasons of a candidate that was cut off by a content filter
+var finishBlocked = []string{"SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY"}
+
+// content is one turn of a generateContent request or response
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text       string      `json:"text,omitempty"`
+	Thought    bool        `json:"thought,omitempty"`
+	InlineData *inlineData `json:"inlineData,omitempty"`
+}
+
+type inlineData struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type generateRequest struct {
+	Contents         []content         `json:"contents"`
+	GenerationConfig *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type generationConfig struct {
+	CandidateCount  int             `json:"candidateCount,omitempty"`
+	Temperature     float64         `json:"temperature,omitempty"`
+	MaxOutputTokens int             `json:"maxOutputTokens,omitempty"`
+	ThinkingConfig  *thinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type thinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+
+type generateResponse struct {
+	Candidates []struct {
+		Index             int                `json:"index"`
+		Content           content            `json:"content"`
+		FinishReason      string             `json:"finishReason"`
+		GroundingMetadata *groundingMetadata `json:"groundingMetadata"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	ResponseID string `json:"responseId"`
+}
+
+type groundingMetadata struct {
+	GroundingChunks []struct {
+		Web *struct {
+			URI   string `json:"uri"`
+			Title string `json:"title"`
+		} `json:"web"`
+	} `json:"groundingChunks"`
+	GroundingSupports []struct {
+		Segment struct {
+			StartIndex int `json:"startIndex"`
+			EndIndex   int `json:"endIndex"`
+		} `json:"segment"`
+		GroundingChunkIndices []int `json:"groundingChunkIndices"`
+	} `json:"groundingSupports"`
+}
+
+type modelList struct {
+	Models        []model `json:"models"`
+	NextPageToken string  `json:"nextPageToken"`
+}
+
+type model struct {
+	Name                       string   `json:"name"`
+	DisplayName                string   `json:"displayName"`
+	Description                string   `json:"description"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	Thinking                   bool     `json:"thinking"`
+}
+
+// info describes an API model, reporting false for models that cannot generate content
+func (m model) info() (providers.ModelInfo, bool) {
+	if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+		return providers.ModelInfo{}, false
+	}
+	info := providers.ModelInfo{
+		ID:           strings.TrimPrefix(m.Name, "models/"),
+		OwnedBy:      "google",
+		Provider:     ProviderName,
+		Name:         m.DisplayName,
+		Description:  m.Description,
+		Capabilities: []string{providers.CapabilityImages, providers.CapabilityFiles},
+	}
+	if m.Thinking {
+		info.Capabilities = append(info.Capabilities, providers.CapabilityThinking)
+	}
+	return info, true
+}
+
+// userContent builds a user turn from a prompt and its attachments
+func userContent(prompt string, files []providers.File) content {
+	turn := content{Role: "user"}
+	if prompt != "" {
+		turn.Parts = append(turn.Parts, part{Text: prompt})
+	}
+	for _, f := range files {
+		turn.Parts = append(turn.Parts, part{InlineData: &inlineData{
+			MIMEType: f.MIMEType,
+			Data:     base64.StdEncoding.EncodeToString(f.Data),
+		}})
+	}
+	return turn
+}
+
+// newGenerateRequest builds the request body for a conversation and its generation options
+func newGenerateRequest(contents []content, config *providers.GenerateConfig) generateRequest {
+	gen := &generationConfig{
+		Temperature:     config.Temperature,
+		MaxOutputTokens: config.MaxTokens,
+	}
+	if config.CandidateCount > 1 {
+		gen.CandidateCount = config.CandidateCount
+	}
+	if config.IncludeThoughts {
+		gen.ThinkingConfig = &thinkingConfig{IncludeThoughts: true}
+	}
+	if *gen == (generationConfig{}) {
+		gen = nil
+	}
+	return generateRequest{Contents: contents, GenerationConfig: gen}
+}
+
+// candidateState collects the streamed parts of one candidate
+type candidateState struct {
+	text         strings.Builder
+	thoughts     strings.Builder
+	images       []providers.Image
+	finishReason string
+	grounding    *groundingMetadata
+}
+
+// accumulator merges the events of a streamed response; it is the providers.EventDecoder
+// of streamGenerateContent
+type accumulator struct {
+	config      *providers.GenerateConfig
+	candidates  map[int]*candidateState
+	blockReason string
+	responseID  string
+}
+
+func newAccumulator(config *providers.GenerateConfig) *accumulator {
+	return &accumulator{config: config, candidates: make(map[int]*candidateState)}
+}
+
+// Decode parses and merges one event
+func (a *accumulator) Decode(data string) (providers.StreamChunk, error) {
+	var event generateResponse
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return providers.StreamChunk{}, fmt.Errorf("%w: stream event: %v", providers.ErrParseFailure, err)
+	}
+	text, thought := a.add(event)
+	return providers.StreamChunk{Text: text, Thought: thought}, nil
+}
+
+// add merges an event and returns the text and thoughts it adds to the first candidate
+func (a *accumulator) add(event generateResponse) (text, thought string) {
+	if event.PromptFeedback != nil && event.PromptFeedback.BlockReason != "" {
+		a.blockReason = event.PromptFeedback.BlockReason
+	}
+	if event.ResponseID != "" {
+		a.responseID = event.ResponseID
+	}
+
+	for _, c := range event.Candidates {
+		state := a.candidates[c.Index]
+		if state == nil {
+			state = &candidateState{}
+			a.candidates[c.Index] = state
+		}
+		for _, p := range c.Content.Parts {
+			switch {
+			case p.Thought:
+				state.thoughts.WriteString(p.Text)
+				if c.Index == 0 {
+					thought += p.Text
+				}
+			case p.InlineData != nil:
+				state.images = append(state.images, providers.Image{
+					URL: "data:" + p.InlineData.MIMEType + ";base64," + p.InlineData.Data,
+				})
+			default:
+				state.text.WriteString(p.Text)
+				if c.Index == 0 {
+					text += p.Text
+				}
+			}
+		}
+		if c.FinishReason != "" {
+			state.finishReason = c.FinishReason
+		}
+		if c.GroundingMetadata != nil {
+			state.grounding = c.GroundingMetadata
+		}
+	}
+	return text, thought
+}
+
+// Response builds the complete response, failing when the prompt or every candidate was blocked
+func (a *accumulator) Response() (*providers.Response, error) {
+	if a.blockReason != "" {
+		return nil, fmt.Errorf("%w: prompt blocked by the Gemini API (%s)", providers.ErrContentBlocked, a.blockReason)
+	}
+	if len(a.candidates) == 0 {
+		return nil, fmt.Errorf("%w: the Gemini API returned no candidates", providers.ErrParseFailure)
+	}
+
+	indexes := make([]int, 0, len(a.candidates))
+	for i := range a.candidates {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+
+	var candidates []providers.Candidate
+	var reasons []string
+	for _, i := range indexes {
+		state := a.candidates[i]
+		text := state.text.String()
+		if text == "" && len(state.images) == 0 && slices.Contains(finishBlocked, state.finishReason) {
+			reasons = append(reasons, state.finishReason)
+			continue
+		}
+		candidate := providers.Candidate{
+			ID:        fmt.Sprintf("%s-%d", a.responseID, i),
+			Content:   text,
+			Images:    state.images,
+			Citations: citations(state.grounding),
+		}
+		if a.config.IncludeThoughts {
+			candidate.Thoughts = state.thoughts.String()
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: answer blocked by the Gemini API (%s)", providers.ErrContentBlocked, strings.Join(reasons, ", "))
+	}
+
+	first := candidates[0]
+	return &providers.Response{
+		Text:       first.Content,
+		Thoughts:   first.Thoughts,
+		Images:     first.Images,
+		Citations:  first.Citations,
+		Candidates: candidates,
+		ResponseID: a.responseID,
+	}, nil
+}
+
+// citations converts search grounding into citations. Segments are byte offsets, as in
+// providers.Citation; sources no segment refers to back the whole answer.
+func citations(grounding *groundingMetadata) []providers.Citation {
+	if grounding == nil {
+		return nil
+	}
+
+	var result []providers.Citation
+	used := make(map[int]bool)
+	for _, support := range grounding.GroundingSupports {
+		for _, i := range support.GroundingChunkIndices {
+			if i < 0 || i >= len(grounding.GroundingChunks) || grounding.GroundingChunks[i].Web == nil {
+				continue
+			}
+			used[i] = true
+			web := grounding.GroundingChunks[i].Web
+			result = append(result, providers.Citation{
+				URL:        web.URI,
+				Title:      web.Title,
+				StartIndex: support.Segment.StartIndex,
+				EndIndex:   support.Segment.EndIndex,
+			})
+		}
+	}
+	for i, chunk := range grounding.GroundingChunks {
+		if chunk.Web != nil && !used[i] {
+			result = append(result, providers.Citation{URL: chunk.Web.URI, Title: chunk.Web.Title})
+		}
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package geminiapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"github.com/imroc/req/v3"
+	"go.uber.org/zap"
+)
+
+// ProviderName is the name the official Gemini API provider is registered under
+const ProviderName = "gemini-api"
+
+// Client implements providers.Provider on top of the official Gemini API
+// (generativelanguage.googleapis.com), authenticated with an API key
+type Client struct {
+	httpClient *req.Client
+	models     *providers.ModelCache
+	log        *zap.Logger
+
+	mu      sync.RWMutex
+	healthy bool
+}
+
+// NewClient creates a client for the API key and base URL of the configuration
+func NewClient(cfg *config.Config, log *zap.Logger) *Client {
+	httpClient := req.NewClient().
+		SetTimeout(2*time.Minute).
+		SetBaseURL(strings.TrimSuffix(cfg.GeminiAPI.BaseURL, "/")).
+		SetCommonHeader("x-goog-api-key", cfg.GeminiAPI.APIKey)
+
+	c := &Client{
+		httpClient: httpClient,
+		log:        log,
+	}
+	c.models = providers.NewModelCache(c.fetchModels, log)
+	return c
+}
+
+// Init checks the API key by listing the models it can use. The client reports itself
+// healthy again after any successful request.
+func (c *Client) Init(ctx context.Context) error {
+	models, err := c.models.Refresh(ctx)
+	c.setHealthy(err == nil)
+	if err != nil {
+		return err
+	}
+	c.log.Info("Gemini API provider initialized", zap.Int("models", len(models)))
+	return nil
+}
+
+func (c *Client) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := c.GenerateContentStream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+func (c *Client) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	config := &providers.GenerateConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+	return c.streamGenerate(ctx, []content{userContent(prompt, config.Files)}, config)
+}
+
+// StartChat creates a session that sends the whole history with every message
+func (c *Client) StartChat(options ...providers.ChatOption) providers.ChatSession {
+	return providers.NewHistorySession[content](chatBackend{client: c}, options...)
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) GetName() string {
+	return ProviderName
+}
+
+func (c *Client) IsHealthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.healthy
+}
+
+// ListModels lists the models of the API that can generate content
+func (c *Client) ListModels() []providers.ModelInfo {
+	return c.models.Models()
+}
+
+func (c *Client) setHealthy(healthy bool) {
+	c.mu.Lock()
+	c.healthy = healthy
+	c.mu.Unlock()
+}
+
+// fetchModels lists every page of the models endpoint
+func (c *Client) fetchModels(ctx context.Context) ([]providers.ModelInfo, error) {
+	var models []providers.ModelInfo
+	pageToken := ""
+	for {
+		var page modelList
+		request := c.httpClient.R().
+			SetContext(ctx).
+			SetQueryParam("pageSize", "1000").
+			SetSuccessResult(&page)
+		if pageToken != "" {
+			request.SetQueryParam("pageToken", pageToken)
+		}
+		resp, err := request.Get("/models")
+		if err != nil {
+			return nil, providers.RequestError("Gemini API list models", err)
+		}
+		if !resp.IsSuccessState() {
+			return nil, c.statusError("list models", resp.StatusCode, resp.Bytes())
+		}
+
+		for _, m := range page.Models {
+			if info, ok := m.info(); ok {
+				models = append(models, info)
+			}
+		}
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// streamGenerate posts a streamGenerateContent request and parses the server-sent events as
+// they arrive. The final chunk carries the complete response.
+func (c *Client) streamGenerate(ctx context.Context, contents []content, config *providers.GenerateConfig) (<-chan providers.StreamChunk, error) {
+	model := strings.TrimPrefix(strings.TrimSpace(config.Model), "models/")
+	if model == "" {
+		return nil, fmt.Errorf("%w: the Gemini API needs a model", providers.ErrUnknownModel)
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		DisableAutoReadResponse().
+		SetQueryParam("alt", "sse").
+		SetBody(newGenerateRequest(contents, config)).
+		Post("/models/" + model + ":streamGenerateContent")
+	if err != nil {
+		return nil, providers.RequestError("Gemini API generate", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, c.statusError("generate", resp.StatusCode, body)
+	}
+	c.setHealthy(true)
+
+	return providers.StreamEvents(ctx, resp.Body, newAccumulator(config), config.IncludeThoughts, "Gemini API generate"), nil
+}
@@ -0,0 +1,273 @@
+package geminiapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+// newTestClient starts a stand-in Gemini API serving handler and returns a client for it
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(&config.Config{GeminiAPI: config.GeminiAPIConfig{APIKey: "test-key", BaseURL: server.URL + "/v1beta"}}, zap.NewNop())
+}
+
+// writeEvents writes server-sent events, one per JSON value, compacted onto a single line
+func writeEvents(w http.ResponseWriter, data ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, d := range data {
+		var line bytes.Buffer
+		if err := json.Compact(&line, []byte(d)); err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(w, "data: %s\r\n\r\n", line.String())
+	}
+}
+
+func TestListModelsPaginates(t *testing.T) {
+	pages := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want the API key", got)
+		}
+		pages++
+		switch r.URL.Query().Get("pageToken") {
+		case "":
+			fmt.Fprint(w, `{"models":[
+				{"name":"models/gemini-2.5-flash","displayName":"Gemini 2.5 Flash","supportedGenerationMethods":["generateContent","countTokens"],"thinking":true},
+				{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}
+			],"nextPageToken":"page-2"}`)
+		case "page-2":
+			fmt.Fprint(w, `{"models":[{"name":"models/gemini-2.0-flash","supportedGenerationMethods":["generateContent"]}]}`)
+		default:
+			t.Errorf("unexpected page token %q", r.URL.Query().Get("pageToken"))
+		}
+	})
+
+	if err := c.Init(context.Background()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	models := c.ListModels()
+	if pages != 2 {
+		t.Errorf("fetched %d pages, want 2 (the list should be cached after Init)", pages)
+	}
+	if len(models) != 2 {
+		t.Fatalf("got %d models, want the 2 that generate content: %+v", len(models), models)
+	}
+	if models[0].ID != "gemini-2.5-flash" || models[0].Name != "Gemini 2.5 Flash" || models[0].Provider != ProviderName {
+		t.Errorf("unexpected first model %+v", models[0])
+	}
+	if !strings.Contains(strings.Join(models[0].Capabilities, ","), providers.CapabilityThinking) {
+		t.Errorf("thinking model without the thinking capability: %+v", models[0])
+	}
+	if models[1].ID != "gemini-2.0-flash" {
+		t.Errorf("unexpected second model %+v", models[1])
+	}
+}
+
+func TestGenerateContent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var body generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		if len(body.Contents) != 1 || body.Contents[0].Role != "user" || body.Contents[0].Parts[0].Text != "Who won?" {
+			t.Errorf("unexpected contents %+v", body.Contents)
+		}
+		if len(body.Contents[0].Parts) != 2 || body.Contents[0].Parts[1].InlineData == nil || body.Contents[0].Parts[1].InlineData.Data != "aGk=" {
+			t.Errorf("attachment not sent inline: %+v", body.Contents[0].Parts)
+		}
+		writeEvents(w, `{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"Spain won."}]},"finishReason":"STOP",
+			"groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com/final","title":"Final"}},{"web":{"uri":"https://example.com/other","title":"Other"}}],
+			"groundingSupports":[{"segment":{"startIndex":0,"endIndex":10},"groundingChunkIndices":[0]}]}}],"responseId":"resp-1"}`)
+	})
+
+	response, err := c.GenerateContent(context.Background(), "Who won?",
+		providers.WithModel("models/gemini-2.5-flash"),
+		providers.WithFiles([]providers.File{{Name: "hi.txt", MIMEType: "text/plain", Data: []byte("hi")}}))
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	if response.Text != "Spain won." || response.ResponseID != "resp-1" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	want := []providers.Citation{
+		{URL: "https://example.com/final", Title: "Final", StartIndex: 0, EndIndex: 10},
+		{URL: "https://example.com/other", Title: "Other"},
+	}
+	if len(response.Citations) != len(want) {
+		t.Fatalf("got citations %+v, want %+v", response.Citations, want)
+	}
+	for i := range want {
+		if response.Citations[i] != want[i] {
+			t.Errorf("citation %d = %+v, want %+v", i, response.Citations[i], want[i])
+		}
+	}
+}
+
+func TestGenerateContentStreamCandidates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		if body.GenerationConfig == nil || body.GenerationConfig.CandidateCount != 2 || body.GenerationConfig.ThinkingConfig == nil {
+			t.Errorf("unexpected generation config %+v", body.GenerationConfig)
+		}
+		writeEvents(w,
+			`{"candidates":[{"index":0,"content":{"parts":[{"text":"Weighing it up.","thought":true}]}}],"responseId":"resp-2"}`,
+			`{"candidates":[{"index":0,"content":{"parts":[{"text":"Tea"}]}},{"index":1,"content":{"parts":[{"text":"Coffee"}]}}]}`,
+			`{"candidates":[{"index":0,"content":{"parts":[{"text":", please."}]},"finishReason":"STOP"},{"index":1,"content":{"parts":[{"text":"!"}]},"finishReason":"STOP"}]}`,
+		)
+	})
+
+	stream, err := c.GenerateContentStream(context.Background(), "Tea or coffee?",
+		providers.WithModel("gemini-2.5-flash"), providers.WithCandidateCount(2), providers.WithThoughts(true))
+	if err != nil {
+		t.Fatalf("GenerateContentStream: %v", err)
+	}
+
+	var deltas []string
+	var thoughts strings.Builder
+	var final *providers.Response
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		if chunk.Text != "" {
+			deltas = append(deltas, chunk.Text)
+		}
+		thoughts.WriteString(chunk.Thought)
+		if chunk.Response != nil {
+			final = chunk.Response
+		}
+	}
+
+	if strings.Join(deltas, "|") != "Tea|, please." {
+		t.Errorf("streamed deltas %q, want only those of the first candidate", deltas)
+	}
+	if thoughts.String() != "Weighing it up." {
+		t.Errorf("streamed thoughts %q", thoughts.String())
+	}
+	if final == nil {
+		t.Fatal("no final response")
+	}
+	if final.Text != "Tea, please." || final.Thoughts != "Weighing it up." {
+		t.Errorf("unexpected final response %+v", final)
+	}
+	if len(final.Candidates) != 2 || final.Candidates[1].Content != "Coffee!" || final.Candidates[1].ID != "resp-2-1" {
+		t.Errorf("unexpected candidates %+v", final.Candidates)
+	}
+}
+
+func TestGenerateContentBlocked(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+	}{
+		{"prompt", `{"promptFeedback":{"blockReason":"SAFETY"}}`},
+		{"answer", `{"candidates":[{"index":0,"content":{"parts":[]},"finishReason":"RECITATION"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeEvents(w, tt.event)
+			})
+			_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("gemini-2.5-flash"))
+			if !errors.Is(err, providers.ErrContentBlocked) {
+				t.Errorf("error %v, want ErrContentBlocked", err)
+			}
+		})
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"invalid key", http.StatusBadRequest, `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`, providers.ErrAuthExpired},
+		{"forbidden", http.StatusForbidden, `{"error":{"code":403,"message":"Permission denied","status":"PERMISSION_DENIED"}}`, providers.ErrAuthExpired},
+		{"unknown model", http.StatusNotFound, `{"error":{"code":404,"message":"models/nope is not found","status":"NOT_FOUND"}}`, providers.ErrUnknownModel},
+		{"quota", http.StatusTooManyRequests, `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`, providers.ErrRateLimited},
+		{"deadline", http.StatusGatewayTimeout, `{"error":{"code":504,"message":"Deadline exceeded","status":"DEADLINE_EXCEEDED"}}`, providers.ErrTimeout},
+		{"overloaded", http.StatusServiceUnavailable, `{"error":{"code":503,"message":"The model is overloaded","status":"UNAVAILABLE"}}`, providers.ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			c.setHealthy(true)
+
+			_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("gemini-2.5-flash"))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error %v, want %v", err, tt.want)
+			}
+			if healthy := c.IsHealthy(); healthy == (tt.want == providers.ErrAuthExpired) {
+				t.Errorf("healthy = %v after %v", healthy, err)
+			}
+		})
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"Invalid JSON payload","status":"INVALID_ARGUMENT"}}`)
+	})
+	_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("gemini-2.5-flash"))
+	if err == nil || providers.ErrorClass(err) != providers.ClassOther || !strings.Contains(err.Error(), "Invalid JSON payload") {
+		t.Errorf("error %v, want an unclassified error with the API message", err)
+	}
+}
+
+func TestChatSessionResendsHistory(t *testing.T) {
+	var requests []generateRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		requests = append(requests, body)
+		writeEvents(w, fmt.Sprintf(`{"candidates":[{"index":0,"content":{"parts":[{"text":"answer %d"}]}}]}`, len(requests)))
+	})
+
+	session := c.StartChat(providers.WithChatModel("gemini-2.5-flash"))
+	for _, text := range []string{"first", "second"} {
+		if _, err := session.SendMessage(context.Background(), text); err != nil {
+			t.Fatalf("SendMessage(%q): %v", text, err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	contents := requests[1].Contents
+	if len(contents) != 3 || contents[1].Role != "model" || contents[1].Parts[0].Text != "answer 1" || contents[2].Parts[0].Text != "second" {
+		t.Errorf("second request sent %+v, want the first exchange before the new message", contents)
+	}
+}
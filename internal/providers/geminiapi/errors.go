@@ -0,0 +1,39 @@
+package geminiapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// apiError is the error body of the Gemini API
+type apiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// statusError classifies a non-200 API response. A rejected API key marks the client unhealthy.
+func (c *Client) statusError(op string, status int, body []byte) error {
+	var apiErr apiError
+	_ = json.Unmarshal(body, &apiErr)
+	detail := apiErr.Error.Message
+
+	var err error
+	if status == http.StatusBadRequest && strings.Contains(detail, "API key") {
+		// An invalid key is reported as a bad request
+		err = fmt.Errorf("%w: Gemini API %s failed with status %d: %s", providers.ErrAuthExpired, op, status, detail)
+	} else {
+		err = providers.StatusError("Gemini API "+op, status, detail)
+	}
+	if errors.Is(err, providers.ErrAuthExpired) {
+		c.setHealthy(false)
+	}
+	return err
+}
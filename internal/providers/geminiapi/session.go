@@ -0,0 +1,24 @@
+package geminiapi
+
+import (
+	"context"
+
+	"ai-bridges/internal/providers"
+)
+
+// chatBackend sends the conversations of chat sessions as generateContent contents
+type chatBackend struct {
+	client *Client
+}
+
+func (b chatBackend) UserTurn(message string, files []providers.File) (content, error) {
+	return userContent(message, files), nil
+}
+
+func (b chatBackend) ModelTurn(text string) content {
+	return content{Role: "model", Parts: []part{{Text: text}}}
+}
+
+func (b chatBackend) Send(ctx context.Context, turns []content, config *providers.GenerateConfig) (<-chan providers.StreamChunk, error) {
+	return b.client.streamGenerate(ctx, turns, config)
+}
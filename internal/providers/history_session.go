@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// HistoryBackend sends the conversation of a HistorySession in a provider's wire format,
+// where T is one turn of it
+type HistoryBackend[T any] interface {
+	// UserTurn builds the turn of a user message and its attachments
+	UserTurn(message string, files []File) (T, error)
+
+	// ModelTurn builds the turn of an answer
+	ModelTurn(text string) T
+
+	// Send streams the answer to a conversation ending with a user turn
+	Send(ctx context.Context, turns []T, config *GenerateConfig) (<-chan StreamChunk, error)
+}
+
+// HistorySession implements ChatSession for stateless APIs: every message is sent along
+// with the turns before it. Sessions live in memory; restored metadata only brings back the model.
+type HistorySession[T any] struct {
+	backend    HistoryBackend[T]
+	model      string
+	metadata   *SessionMetadata
+	turns      []T // turns sent upstream, attachments included
+	history    []Message
+	candidates []Candidate // candidates of the last response
+}
+
+// NewHistorySession starts a session whose messages are sent through backend
+func NewHistorySession[T any](backend HistoryBackend[T], options ...ChatOption) *HistorySession[T] {
+	config := &ChatConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	metadata := &SessionMetadata{ConversationID: uuid.New().String()}
+	if config.Metadata != nil {
+		metadata = config.Metadata
+	}
+	model := config.Model
+	if model == "" {
+		model = metadata.Model
+	}
+
+	return &HistorySession[T]{
+		backend:  backend,
+		model:    model,
+		metadata: metadata,
+	}
+}
+
+// SendMessage sends a message in the chat session
+func (s *HistorySession[T]) SendMessage(ctx context.Context, message string, options ...GenerateOption) (*Response, error) {
+	stream, err := s.SendMessageStream(ctx, message, options...)
+	if err != nil {
+		return nil, err
+	}
+	return CollectStream(stream)
+}
+
+// SendMessageStream sends a message in the chat session and streams the reply.
+// History is updated once the final response arrives.
+func (s *HistorySession[T]) SendMessageStream(ctx context.Context, message string, options ...GenerateOption) (<-chan StreamChunk, error) {
+	config := &GenerateConfig{
+		Model: s.model,
+	}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	turn, err := s.backend.UserTurn(message, config.Files)
+	if err != nil {
+		return nil, err
+	}
+	turns := append(append([]T{}, s.turns...), turn)
+	chunks, err := s.backend.Send(ctx, turns, config)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			if chunk.Response != nil {
+				s.recordTurn(turn, message, chunk.Response)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// recordTurn appends a completed exchange to the session
+func (s *HistorySession[T]) recordTurn(turn T, message string, response *Response) {
+	s.metadata.ResponseID = response.ResponseID
+	s.candidates = response.Candidates
+	s.turns = append(s.turns, turn, s.backend.ModelTurn(response.Text))
+	s.history = append(s.history,
+		Message{Role: "user", Content: message},
+		Message{Role: "model", Content: response.Text, Images: response.Images},
+	)
+}
+
+// ChooseCandidate makes the next message continue from another candidate of the last response
+func (s *HistorySession[T]) ChooseCandidate(index int) error {
+	if index < 0 || index >= len(s.candidates) {
+		return fmt.Errorf("candidate index %d out of range (%d candidates)", index, len(s.candidates))
+	}
+
+	candidate := s.candidates[index]
+	s.metadata.ChoiceID = candidate.ID
+	if n := len(s.turns); n > 0 {
+		s.turns[n-1] = s.backend.ModelTurn(candidate.Content)
+	}
+	if n := len(s.history); n > 0 && s.history[n-1].Role == "model" {
+		s.history[n-1].Content = candidate.Content
+		s.history[n-1].Images = candidate.Images
+	}
+	return nil
+}
+
+// GetMetadata returns session metadata
+func (s *HistorySession[T]) GetMetadata() *SessionMetadata {
+	s.metadata.Model = s.model
+	return s.metadata
+}
+
+// GetHistory returns conversation history
+func (s *HistorySession[T]) GetHistory() []Message {
+	return s.history
+}
+
+// Clear clears the conversation history
+func (s *HistorySession[T]) Clear() {
+	s.turns = nil
+	s.history = []Message{}
+	s.candidates = nil
+	s.metadata = &SessionMetadata{ConversationID: s.metadata.ConversationID}
+}
@@ -6,19 +6,25 @@ import "context"
 type Provider interface {
 	// Init initializes the provider with authentication
 	Init(ctx context.Context) error
-	
+
 	// GenerateContent generates a single response
 	GenerateContent(ctx context.Context, prompt string, options ...GenerateOption) (*Response, error)
-	
+
+	// GenerateContentStream generates a response and delivers it incrementally.
+	// The channel is closed after the final chunk or after a chunk carrying an error.
+	// Callers must read it until it is closed or cancel ctx; providers may hold
+	// upstream resources until then.
+	GenerateContentStream(ctx context.Context, prompt string, options ...GenerateOption) (<-chan StreamChunk, error)
+
 	// StartChat creates a new chat session
 	StartChat(options ...ChatOption) ChatSession
-	
+
 	// Close cleans up resources
 	Close() error
-	
+
 	// GetName returns the provider name
 	GetName() string
-	
+
 	// IsHealthy checks if the provider is ready to serve requests
 	IsHealthy() bool
 
@@ -30,48 +36,80 @@ type Provider interface {
 type ChatSession interface {
 	// SendMessage sends a message and returns the response
 	SendMessage(ctx context.Context, message string, options ...GenerateOption) (*Response, error)
-	
+
+	// SendMessageStream sends a message and delivers the response incrementally. Like
+	// GenerateContentStream, the channel must be read until it is closed or ctx cancelled.
+	SendMessageStream(ctx context.Context, message string, options ...GenerateOption) (<-chan StreamChunk, error)
+
 	// GetMetadata returns session metadata for persistence
 	GetMetadata() *SessionMetadata
-	
+
 	// GetHistory returns the conversation history
 	GetHistory() []Message
-	
+
+	// ChooseCandidate continues the conversation from another candidate of the last response
+	ChooseCandidate(index int) error
+
 	// Clear clears the conversation history
 	Clear()
 }
 
 // Response represents a provider's response
 type Response struct {
-	Text          string              `json:"text"`
-	Images        []Image             `json:"images,omitempty"`
-	Candidates    []Candidate         `json:"candidates,omitempty"`
-	Metadata      map[string]any      `json:"metadata,omitempty"`
-	ChosenIndex   int                 `json:"chosen_index"`
-	ConversationID string             `json:"conversation_id,omitempty"`
-	ResponseID    string              `json:"response_id,omitempty"`
+	Text           string         `json:"text"`
+	Thoughts       string         `json:"thoughts,omitempty"` // reasoning summary, when requested
+	Images         []Image        `json:"images,omitempty"`
+	Citations      []Citation     `json:"citations,omitempty"`
+	Candidates     []Candidate    `json:"candidates,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	ChosenIndex    int            `json:"chosen_index"`
+	ConversationID string         `json:"conversation_id,omitempty"`
+	ResponseID     string         `json:"response_id,omitempty"`
+}
+
+// StreamChunk is one incremental piece of a streamed response.
+// Text and Thought hold only what was generated since the previous chunk. The last chunk
+// of a successful stream carries the complete Response; a failed stream ends
+// with a chunk whose Err is set.
+type StreamChunk struct {
+	Text     string
+	Thought  string // reasoning generated since the previous chunk
+	Response *Response
+	Err      error
 }
 
 // Message represents a single message in conversation
 type Message struct {
-	Role    string   `json:"role"`    // "user" or "model"
-	Content string   `json:"content"`
-	Images  []Image  `json:"images,omitempty"`
+	Role    string  `json:"role"` // "user" or "model"
+	Content string  `json:"content"`
+	Images  []Image `json:"images,omitempty"`
 }
 
 // Image represents an image in the response
 type Image struct {
-	URL         string `json:"url"`
-	Title       string `json:"title,omitempty"`
-	AltText     string `json:"alt_text,omitempty"`
-	Width       int    `json:"width,omitempty"`
-	Height      int    `json:"height,omitempty"`
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	AltText string `json:"alt_text,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+}
+
+// Citation is a source the answer is grounded in. StartIndex and EndIndex are byte offsets
+// of the supported span in the text; both are zero when the source backs the whole answer.
+type Citation struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
 }
 
 // Candidate represents an alternative response
 type Candidate struct {
-	ID      string `json:"id"`
-	Content string `json:"content"`
+	ID        string     `json:"id"`
+	Content   string     `json:"content"`
+	Thoughts  string     `json:"thoughts,omitempty"`
+	Images    []Image    `json:"images,omitempty"`
+	Citations []Citation `json:"citations,omitempty"`
 }
 
 // SessionMetadata contains information to restore a session
@@ -86,12 +124,23 @@ type SessionMetadata struct {
 // GenerateOption configures generation behavior
 type GenerateOption func(*GenerateConfig)
 
+// File is binary content attached to a prompt, such as an image, PDF or text file
+type File struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"-"`
+}
+
 // GenerateConfig holds generation configuration
 type GenerateConfig struct {
-	Model       string
-	Files       []string
-	Temperature float64
-	MaxTokens   int
+	Model           string
+	Files           []File
+	Temperature     float64
+	MaxTokens       int
+	CandidateCount  int
+	IncludeThoughts bool
+	Locale          string // language of the answer, e.g. "en" or "pt-BR"; empty uses the provider default
+	Account         int    // account of a pooled provider to use; 0 lets the provider pick
 }
 
 // ChatOption configures chat session behavior
@@ -110,13 +159,41 @@ func WithModel(model string) GenerateOption {
 	}
 }
 
-// WithFiles adds files to the request
-func WithFiles(files []string) GenerateOption {
+// WithFiles attaches files to the request
+func WithFiles(files []File) GenerateOption {
 	return func(c *GenerateConfig) {
 		c.Files = files
 	}
 }
 
+// WithCandidateCount limits how many candidates the response carries (0 means all)
+func WithCandidateCount(n int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.CandidateCount = n
+	}
+}
+
+// WithThoughts controls whether the model's reasoning is included in the response
+func WithThoughts(include bool) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.IncludeThoughts = include
+	}
+}
+
+// WithLocale sets the language and market the answer is localized for
+func WithLocale(locale string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Locale = locale
+	}
+}
+
+// WithAccount pins the request to one account of a pooled provider
+func WithAccount(id int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Account = id
+	}
+}
+
 // WithChatModel sets the model for chat session
 func WithChatModel(model string) ChatOption {
 	return func(c *ChatConfig) {
@@ -0,0 +1,252 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"github.com/google/uuid"
+)
+
+// ProviderName is the name the mock provider is registered under
+const ProviderName = "mock"
+
+// errorDirectiveRe matches a "[mock-error:<class>]" directive in a prompt, which makes that
+// request fail with the error class
+var errorDirectiveRe = regexp.MustCompile(`\[mock-error:([a-z_]+)\]`)
+
+// classErrors maps the error classes that can be injected to the errors returned
+var classErrors = map[string]error{
+	providers.ClassAuthExpired:    providers.ErrAuthExpired,
+	providers.ClassUnknownModel:   providers.ErrUnknownModel,
+	providers.ClassRateLimited:    providers.ErrRateLimited,
+	providers.ClassContentBlocked: providers.ErrContentBlocked,
+	providers.ClassUnavailable:    providers.ErrUpstreamUnavailable,
+	providers.ClassParseFailure:   providers.ErrParseFailure,
+	providers.ClassTimeout:        providers.ErrTimeout,
+}
+
+// Provider is a deterministic providers.Provider for development and CI. It answers by
+// echoing the prompt, by replaying a script or by rendering a template, and can add latency,
+// stream word by word and fail on purpose.
+type Provider struct {
+	mode       string
+	script     []string
+	template   *template.Template
+	latency    time.Duration
+	chunkDelay time.Duration
+	errorEvery int
+	err        error
+
+	requests atomic.Int64
+}
+
+// templateData is what answer templates can refer to
+type templateData struct {
+	Prompt string
+	Model  string
+	Locale string
+	Count  int64 // number of the request, starting at 1
+}
+
+// New creates the mock provider, reading the script file or parsing the template of its mode
+func New(cfg *config.Config) (*Provider, error) {
+	p := &Provider{
+		mode:       cfg.Mock.Mode,
+		latency:    time.Duration(cfg.Mock.Latency) * time.Millisecond,
+		chunkDelay: time.Duration(cfg.Mock.ChunkDelay) * time.Millisecond,
+		errorEvery: cfg.Mock.ErrorEvery,
+		err:        classErrors[cfg.Mock.Error],
+	}
+
+	switch p.mode {
+	case "script":
+		data, err := os.ReadFile(cfg.Mock.ScriptFile)
+		if err != nil {
+			return nil, fmt.Errorf("read mock script: %w", err)
+		}
+		if err := json.Unmarshal(data, &p.script); err != nil {
+			return nil, fmt.Errorf("parse mock script %s (must be a JSON array of strings): %w", cfg.Mock.ScriptFile, err)
+		}
+		if len(p.script) == 0 {
+			return nil, fmt.Errorf("mock script %s has no answers", cfg.Mock.ScriptFile)
+		}
+	case "template":
+		tmpl, err := template.New("mock").Parse(cfg.Mock.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse MOCK_TEMPLATE: %w", err)
+		}
+		p.template = tmpl
+	}
+	return p, nil
+}
+
+func (p *Provider) Init(ctx context.Context) error {
+	return nil
+}
+
+func (p *Provider) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := p.GenerateContentStream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+// GenerateContentStream answers after the configured latency and streams the answer word by word
+func (p *Provider) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	config := &providers.GenerateConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	count := p.requests.Add(1)
+	if err := p.injectedError(prompt, count); err != nil {
+		return nil, err
+	}
+	response, err := p.respond(prompt, config, count)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan providers.StreamChunk)
+	go func() {
+		defer close(chunks)
+		send := func(chunk providers.StreamChunk, delay time.Duration) bool {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		delay := p.latency
+		if response.Thoughts != "" {
+			if !send(providers.StreamChunk{Thought: response.Thoughts}, delay) {
+				return
+			}
+			delay = p.chunkDelay
+		}
+		for _, word := range strings.SplitAfter(response.Text, " ") {
+			if word == "" {
+				continue
+			}
+			if !send(providers.StreamChunk{Text: word}, delay) {
+				return
+			}
+			delay = p.chunkDelay
+		}
+		send(providers.StreamChunk{Response: response}, 0)
+	}()
+	return chunks, nil
+}
+
+// StartChat creates a session that keeps its history in memory
+func (p *Provider) StartChat(options ...providers.ChatOption) providers.ChatSession {
+	config := &providers.ChatConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	metadata := &providers.SessionMetadata{ConversationID: uuid.New().String()}
+	if config.Metadata != nil {
+		metadata = config.Metadata
+	}
+	model := config.Model
+	if model == "" {
+		model = metadata.Model
+	}
+	return &ChatSession{provider: p, model: model, metadata: metadata}
+}
+
+func (p *Provider) Close() error {
+	return nil
+}
+
+func (p *Provider) GetName() string {
+	return ProviderName
+}
+
+func (p *Provider) IsHealthy() bool {
+	return true
+}
+
+// ListModels lists the single model the mock serves under its own name
+func (p *Provider) ListModels() []providers.ModelInfo {
+	return []providers.ModelInfo{{
+		ID:          ProviderName,
+		OwnedBy:     ProviderName,
+		Provider:    ProviderName,
+		Name:        "Mock",
+		Description: "Deterministic answers for development and CI (" + p.mode + " mode)",
+	}}
+}
+
+// injectedError returns the error a request fails with: the class named by a directive in the
+// prompt, or the configured error on every n-th request
+func (p *Provider) injectedError(prompt string, count int64) error {
+	if m := errorDirectiveRe.FindStringSubmatch(prompt); m != nil {
+		if err, ok := classErrors[m[1]]; ok {
+			return fmt.Errorf("%w: injected by the mock provider", err)
+		}
+	}
+	if p.errorEvery > 0 && count%int64(p.errorEvery) == 0 {
+		return fmt.Errorf("%w: injected by the mock provider (request %d)", p.err, count)
+	}
+	return nil
+}
+
+// respond builds the response to a request. Every candidate carries the same answer.
+func (p *Provider) respond(prompt string, config *providers.GenerateConfig, count int64) (*providers.Response, error) {
+	var text string
+	switch p.mode {
+	case "script":
+		text = p.script[(count-1)%int64(len(p.script))]
+	case "template":
+		var b strings.Builder
+		data := templateData{Prompt: prompt, Model: config.Model, Locale: config.Locale, Count: count}
+		if err := p.template.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("render mock template: %w", err)
+		}
+		text = b.String()
+	default:
+		text = prompt
+	}
+
+	var thoughts string
+	if config.IncludeThoughts {
+		thoughts = fmt.Sprintf("Mock reasoning for request %d.", count)
+	}
+
+	n := max(config.CandidateCount, 1)
+	responseID := fmt.Sprintf("mock-%d", count)
+	candidates := make([]providers.Candidate, n)
+	for i := range candidates {
+		candidates[i] = providers.Candidate{
+			ID:       fmt.Sprintf("%s-%d", responseID, i),
+			Content:  text,
+			Thoughts: thoughts,
+		}
+	}
+	return &providers.Response{
+		Text:       text,
+		Thoughts:   thoughts,
+		Candidates: candidates,
+		ResponseID: responseID,
+	}, nil
+}
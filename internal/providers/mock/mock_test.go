@@ -0,0 +1,203 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+)
+
+func newProvider(t *testing.T, mock config.MockConfig) *Provider {
+	t.Helper()
+	p, err := New(&config.Config{Mock: mock})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p
+}
+
+// writeScript writes a script file and returns its path
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func generate(t *testing.T, p *Provider, prompt string, options ...providers.GenerateOption) string {
+	t.Helper()
+	response, err := p.GenerateContent(context.Background(), prompt, options...)
+	if err != nil {
+		t.Fatalf("GenerateContent(%q): %v", prompt, err)
+	}
+	return response.Text
+}
+
+func TestEcho(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "echo"})
+
+	response, err := p.GenerateContent(context.Background(), "Say hello", providers.WithCandidateCount(2), providers.WithThoughts(true))
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	if response.Text != "Say hello" || response.ResponseID != "mock-1" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if response.Thoughts != "Mock reasoning for request 1." {
+		t.Errorf("thoughts %q", response.Thoughts)
+	}
+	if len(response.Candidates) != 2 || response.Candidates[1].ID != "mock-1-1" || response.Candidates[1].Content != "Say hello" {
+		t.Errorf("unexpected candidates %+v", response.Candidates)
+	}
+
+	// Without a mode the prompt is echoed too
+	if got := generate(t, newProvider(t, config.MockConfig{}), "again"); got != "again" {
+		t.Errorf("answered %q", got)
+	}
+}
+
+func TestScript(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "script", ScriptFile: writeScript(t, `["first", "second"]`)})
+
+	var answers []string
+	for range 3 {
+		answers = append(answers, generate(t, p, "ignored"))
+	}
+	// The script starts over once every answer was given
+	if got := strings.Join(answers, ","); got != "first,second,first" {
+		t.Errorf("answered %s", got)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.json"), "read mock script"},
+		{"not an array of strings", writeScript(t, `[1, 2]`), "must be a JSON array of strings"},
+		{"no answers", writeScript(t, `[]`), "has no answers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&config.Config{Mock: config.MockConfig{Mode: "script", ScriptFile: tt.file}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "template", Template: "#{{.Count}} {{.Model}}/{{.Locale}}: {{.Prompt}}"})
+
+	generate(t, p, "first")
+	if got := generate(t, p, "hello", providers.WithModel("gemini-2.5-pro"), providers.WithLocale("pt-BR")); got != "#2 gemini-2.5-pro/pt-BR: hello" {
+		t.Errorf("answered %q", got)
+	}
+
+	if _, err := New(&config.Config{Mock: config.MockConfig{Mode: "template", Template: "{{.Prompt"}}); err == nil || !strings.Contains(err.Error(), "parse MOCK_TEMPLATE") {
+		t.Errorf("invalid template error %v", err)
+	}
+	broken := newProvider(t, config.MockConfig{Mode: "template", Template: "{{.Missing}}"})
+	if _, err := broken.GenerateContent(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "render mock template") {
+		t.Errorf("render error %v", err)
+	}
+}
+
+func TestErrorDirective(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "echo"})
+
+	for class, want := range classErrors {
+		t.Run(class, func(t *testing.T) {
+			_, err := p.GenerateContent(context.Background(), "hello [mock-error:"+class+"]")
+			if !errors.Is(err, want) {
+				t.Errorf("error %v, want %v", err, want)
+			}
+			if got := providers.ErrorClass(err); got != class {
+				t.Errorf("error class %q, want %q", got, class)
+			}
+		})
+	}
+
+	// Unknown classes are not directives and are echoed like the rest of the prompt
+	if got := generate(t, p, "[mock-error:sometimes]"); got != "[mock-error:sometimes]" {
+		t.Errorf("answered %q", got)
+	}
+}
+
+func TestErrorEvery(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "echo", ErrorEvery: 3, Error: providers.ClassRateLimited})
+
+	var failed []int
+	for i := 1; i <= 6; i++ {
+		_, err := p.GenerateContent(context.Background(), "hello")
+		if err != nil {
+			if !errors.Is(err, providers.ErrRateLimited) {
+				t.Errorf("request %d failed with %v, want ErrRateLimited", i, err)
+			}
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) != 2 || failed[0] != 3 || failed[1] != 6 {
+		t.Errorf("requests %v failed, want 3 and 6", failed)
+	}
+}
+
+func TestStreamWordByWord(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "echo", ChunkDelay: 1})
+
+	chunks, err := p.GenerateContentStream(context.Background(), "one two three", providers.WithThoughts(true))
+	if err != nil {
+		t.Fatalf("GenerateContentStream: %v", err)
+	}
+	var got []providers.StreamChunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+
+	want := []providers.StreamChunk{
+		{Thought: "Mock reasoning for request 1."},
+		{Text: "one "},
+		{Text: "two "},
+		{Text: "three"},
+	}
+	if len(got) != len(want)+1 {
+		t.Fatalf("got %d chunks, want %d and the response: %+v", len(got), len(want), got)
+	}
+	for i, chunk := range want {
+		if got[i].Text != chunk.Text || got[i].Thought != chunk.Thought || got[i].Response != nil {
+			t.Errorf("chunk %d is %+v, want %+v", i, got[i], chunk)
+		}
+	}
+	if final := got[len(got)-1].Response; final == nil || final.Text != "one two three" {
+		t.Errorf("last chunk %+v, want the whole response", got[len(got)-1])
+	}
+}
+
+func TestStreamStopsWhenCancelled(t *testing.T) {
+	p := newProvider(t, config.MockConfig{Mode: "echo", Latency: 10_000})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks, err := p.GenerateContentStream(ctx, "hello")
+	if err != nil {
+		t.Fatalf("GenerateContentStream: %v", err)
+	}
+	cancel()
+
+	select {
+	case chunk, ok := <-chunks:
+		if ok {
+			t.Errorf("got %+v after cancelling", chunk)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not closed after cancelling")
+	}
+}
@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"ai-bridges/internal/providers"
+)
+
+// ChatSession implements providers.ChatSession for the mock provider. Each message is
+// answered on its own; the history is only recorded.
+type ChatSession struct {
+	provider   *Provider
+	model      string
+	metadata   *providers.SessionMetadata
+	history    []providers.Message
+	candidates []providers.Candidate // candidates of the last response
+}
+
+// SendMessage sends a message in the chat session
+func (s *ChatSession) SendMessage(ctx context.Context, message string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := s.SendMessageStream(ctx, message, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+// SendMessageStream sends a message in the chat session and streams the reply.
+// History is updated once the final response arrives.
+func (s *ChatSession) SendMessageStream(ctx context.Context, message string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	options = append([]providers.GenerateOption{providers.WithModel(s.model)}, options...)
+	chunks, err := s.provider.GenerateContentStream(ctx, message, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan providers.StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			if chunk.Response != nil {
+				s.recordTurn(message, chunk.Response)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// recordTurn appends a completed exchange to the session
+func (s *ChatSession) recordTurn(message string, response *providers.Response) {
+	s.metadata.ResponseID = response.ResponseID
+	s.candidates = response.Candidates
+	s.history = append(s.history,
+		providers.Message{Role: "user", Content: message},
+		providers.Message{Role: "model", Content: response.Text},
+	)
+}
+
+// ChooseCandidate makes the next message continue from another candidate of the last response
+func (s *ChatSession) ChooseCandidate(index int) error {
+	if index < 0 || index >= len(s.candidates) {
+		return fmt.Errorf("candidate index %d out of range (%d candidates)", index, len(s.candidates))
+	}
+	s.metadata.ChoiceID = s.candidates[index].ID
+	return nil
+}
+
+// GetMetadata returns session metadata
+func (s *ChatSession) GetMetadata() *providers.SessionMetadata {
+	s.metadata.Model = s.model
+	return s.metadata
+}
+
+// GetHistory returns conversation history
+func (s *ChatSession) GetHistory() []providers.Message {
+	return s.history
+}
+
+// Clear clears the conversation history
+func (s *ChatSession) Clear() {
+	s.history = []providers.Message{}
+	s.candidates = nil
+	s.metadata = &providers.SessionMetadata{ConversationID: s.metadata.ConversationID}
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// modelsCacheTTL is how long a ModelCache reuses the model list before fetching it again
+	modelsCacheTTL = 10 * time.Minute
+
+	// modelsFetchTimeout bounds the model list request made by ModelCache.Models
+	modelsFetchTimeout = 10 * time.Second
+)
+
+// ModelCache keeps the model list of a provider that fetches it from its API
+type ModelCache struct {
+	fetch func(ctx context.Context) ([]ModelInfo, error)
+	log   *zap.Logger
+
+	mu      sync.Mutex
+	models  []ModelInfo
+	fetched time.Time
+}
+
+// NewModelCache creates a cache that lists models with fetch
+func NewModelCache(fetch func(ctx context.Context) ([]ModelInfo, error), log *zap.Logger) *ModelCache {
+	return &ModelCache{fetch: fetch, log: log}
+}
+
+// Refresh fetches the model list now and caches it on success
+func (c *ModelCache) Refresh(ctx context.Context) ([]ModelInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	models, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.models = models
+	c.fetched = time.Now()
+	return models, nil
+}
+
+// Models returns the model list, fetching it again once it is older than modelsCacheTTL.
+// The previous list is kept when fetching fails.
+func (c *ModelCache) Models() []ModelInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && time.Since(c.fetched) < modelsCacheTTL {
+		return c.models
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelsFetchTimeout)
+	defer cancel()
+	models, err := c.fetch(ctx)
+	c.fetched = time.Now()
+	if err != nil {
+		c.log.Warn("Failed to list models", zap.Error(err))
+		return c.models
+	}
+	c.models = models
+	return models
+}
@@ -2,31 +2,128 @@ package providers
 
 // ModelInfo contains basic information about an AI model
 type ModelInfo struct {
-	ID       string `json:"id"`
-	Created  int64  `json:"created"`
-	OwnedBy  string `json:"owned_by"`
-	Provider string `json:"provider"` // "gemini", "claude", etc.
+	ID           string   `json:"id"`
+	Created      int64    `json:"created"`
+	OwnedBy      string   `json:"owned_by"`
+	Provider     string   `json:"provider"` // "gemini", "claude", etc.
+	Name         string   `json:"name,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
-// SupportedModels is the central registry of all models supported by the system.
-// In the future, this could be loaded from a configuration file or database.
-var SupportedModels = []ModelInfo{
+// Model capabilities advertised by routes
+const (
+	CapabilityThinking = "thinking"
+	CapabilityImages   = "images"
+	CapabilityFiles    = "files"
+	CapabilitySearch   = "search"
+)
+
+// geminiCapabilities are shared by every Gemini web model
+var geminiCapabilities = []string{CapabilityThinking, CapabilityImages, CapabilityFiles, CapabilitySearch}
+
+// GemModelPrefix starts the model IDs that select a Gem of the Gemini web provider,
+// e.g. "gem/coding-partner"
+const GemModelPrefix = "gem/"
+
+// DefaultRoutes is the routing table used when MODEL_ROUTES_FILE is not set.
+// Everything is served by the Gemini web provider.
+var DefaultRoutes = []Route{
+	{
+		ID:           "gemini-pro",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		DisplayName:  "Gemini web app default",
+		OwnedBy:      "google",
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "gemini-2.5-flash",
+		Provider:     "gemini",
+		DisplayName:  "Gemini 2.5 Flash",
+		OwnedBy:      "google",
+		Created:      1750118400, // June 17, 2025
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "gemini-2.5-pro",
+		Provider:     "gemini",
+		DisplayName:  "Gemini 2.5 Pro",
+		OwnedBy:      "google",
+		Created:      1750118400,
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "gemini-3.0-pro",
+		Provider:     "gemini",
+		DisplayName:  "Gemini 3 Pro",
+		OwnedBy:      "google",
+		Created:      1763510400, // November 19, 2025
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "gemini-1.5-pro",
+		Provider:     "gemini",
+		Model:        "gemini-2.5-pro",
+		DisplayName:  "Gemini 1.5 Pro (served by Gemini 2.5 Pro)",
+		OwnedBy:      "google",
+		Created:      1715644800, // May 14, 2024
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "gemini-1.5-flash",
+		Provider:     "gemini",
+		Model:        "gemini-2.5-flash",
+		DisplayName:  "Gemini 1.5 Flash (served by Gemini 2.5 Flash)",
+		OwnedBy:      "google",
+		Created:      1715644800,
+		Capabilities: geminiCapabilities,
+	},
+	{
+		// Kept from the first model list, which served it from the web app default
+		ID:           "gpt-4o",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		DisplayName:  "GPT-4o (served by the Gemini web app default)",
+		OwnedBy:      "openai-alias",
+		Created:      1715558400, // May 13, 2024
+		Capabilities: geminiCapabilities,
+	},
+	{
+		// Gems of the accounts, passed through unchanged
+		ID:           GemModelPrefix + "*",
+		Provider:     "gemini",
+		Capabilities: geminiCapabilities,
+	},
+	{
+		ID:           "claude-3-7-sonnet-20250219",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		DisplayName:  "Claude 3.7 Sonnet",
+		Created:      1739923200,
+		Capabilities: geminiCapabilities,
+	},
 	{
-		ID:       "gemini-1.5-pro",
-		Created:  1715644800, // May 14, 2024
-		OwnedBy:  "google",
-		Provider: "gemini",
+		ID:           "claude-3-5-sonnet-20240620",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		DisplayName:  "Claude 3.5 Sonnet",
+		Created:      1718841600,
+		Capabilities: geminiCapabilities,
 	},
 	{
-		ID:       "gemini-1.5-flash",
-		Created:  1715644800,
-		OwnedBy:  "google",
-		Provider: "gemini",
+		ID:           "claude-3-opus-20240229",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		DisplayName:  "Claude 3 Opus",
+		Created:      1709164800,
+		Capabilities: geminiCapabilities,
 	},
 	{
-		ID:       "gpt-4o",
-		Created:  1715558400, // May 13, 2024
-		OwnedBy:  "openai-alias",
-		Provider: "gemini", // Served via Gemini proxy
+		// Claude clients send whatever model they were built for
+		ID:           "claude-*",
+		Provider:     "gemini",
+		Model:        "unspecified",
+		Capabilities: geminiCapabilities,
 	},
 }
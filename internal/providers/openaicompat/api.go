@@ -0,0 +1,204 @@
+package openaicompat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"ai-bridges/internal/providers"
+)
+
+// message is one turn of a chat completion request. Content is a string, or a list of
+// parts when images are attached.
+type message struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	Stream      bool      `json:"stream"`
+	N           int       `json:"n,omitempty"`
+	Temperature float64   `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+type chatChunk struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"` // vLLM, DeepSeek
+			Reasoning        string `json:"reasoning"`         // Ollama, OpenRouter
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type modelList struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// userMessage builds a user turn from a prompt and its attachments. Images are sent as
+// data URLs and text files inline; other files cannot be sent.
+func userMessage(prompt string, files []providers.File) (message, error) {
+	if len(files) == 0 {
+		return message{Role: "user", Content: prompt}, nil
+	}
+
+	var parts []contentPart
+	if prompt != "" {
+		parts = append(parts, contentPart{Type: "text", Text: prompt})
+	}
+	for _, f := range files {
+		switch {
+		case strings.HasPrefix(f.MIMEType, "image/"):
+			parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{
+				URL: "data:" + f.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(f.Data),
+			}})
+		case strings.HasPrefix(f.MIMEType, "text/"):
+			parts = append(parts, contentPart{Type: "text", Text: string(f.Data)})
+		default:
+			return message{}, fmt.Errorf("attachment %q: %s files cannot be sent to OpenAI-compatible backends", f.Name, f.MIMEType)
+		}
+	}
+	return message{Role: "user", Content: parts}, nil
+}
+
+// newChatRequest builds the request body for a conversation and its generation options
+func newChatRequest(messages []message, config *providers.GenerateConfig) chatRequest {
+	request := chatRequest{
+		Model:       config.Model,
+		Messages:    messages,
+		Stream:      true,
+		Temperature: config.Temperature,
+		MaxTokens:   config.MaxTokens,
+	}
+	if config.CandidateCount > 1 {
+		request.N = config.CandidateCount
+	}
+	return request
+}
+
+// choiceState collects the streamed deltas of one choice
+type choiceState struct {
+	text         strings.Builder
+	thoughts     strings.Builder
+	finishReason string
+}
+
+// accumulator merges the events of a streamed chat completion; it is the
+// providers.EventDecoder of chat completions
+type accumulator struct {
+	config  *providers.GenerateConfig
+	choices map[int]*choiceState
+	id      string
+}
+
+func newAccumulator(config *providers.GenerateConfig) *accumulator {
+	return &accumulator{config: config, choices: make(map[int]*choiceState)}
+}
+
+// Decode parses and merges one event. The stream ends with a [DONE] event.
+func (a *accumulator) Decode(data string) (providers.StreamChunk, error) {
+	if data == "[DONE]" {
+		return providers.StreamChunk{}, io.EOF
+	}
+
+	var event chatChunk
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return providers.StreamChunk{}, fmt.Errorf("%w: stream event: %v", providers.ErrParseFailure, err)
+	}
+	if event.Error != nil {
+		return providers.StreamChunk{}, fmt.Errorf("%w: %s", providers.ErrUpstreamUnavailable, event.Error.Message)
+	}
+	text, thought := a.add(event)
+	return providers.StreamChunk{Text: text, Thought: thought}, nil
+}
+
+// add merges an event and returns the text and reasoning it adds to the first choice
+func (a *accumulator) add(event chatChunk) (text, thought string) {
+	if event.ID != "" {
+		a.id = event.ID
+	}
+	for _, choice := range event.Choices {
+		state := a.choices[choice.Index]
+		if state == nil {
+			state = &choiceState{}
+			a.choices[choice.Index] = state
+		}
+		reasoning := choice.Delta.ReasoningContent + choice.Delta.Reasoning
+		state.text.WriteString(choice.Delta.Content)
+		state.thoughts.WriteString(reasoning)
+		if choice.FinishReason != "" {
+			state.finishReason = choice.FinishReason
+		}
+		if choice.Index == 0 {
+			text += choice.Delta.Content
+			thought += reasoning
+		}
+	}
+	return text, thought
+}
+
+// Response builds the complete response, failing when every choice was filtered
+func (a *accumulator) Response() (*providers.Response, error) {
+	if len(a.choices) == 0 {
+		return nil, fmt.Errorf("%w: the backend returned no choices", providers.ErrParseFailure)
+	}
+
+	indexes := make([]int, 0, len(a.choices))
+	for i := range a.choices {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+
+	var candidates []providers.Candidate
+	for _, i := range indexes {
+		state := a.choices[i]
+		if state.text.Len() == 0 && state.finishReason == "content_filter" {
+			continue
+		}
+		candidate := providers.Candidate{
+			ID:      fmt.Sprintf("%s-%d", a.id, i),
+			Content: state.text.String(),
+		}
+		if a.config.IncludeThoughts {
+			candidate.Thoughts = state.thoughts.String()
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: answer filtered by the backend", providers.ErrContentBlocked)
+	}
+
+	first := candidates[0]
+	return &providers.Response{
+		Text:       first.Content,
+		Thoughts:   first.Thoughts,
+		Candidates: candidates,
+		ResponseID: a.id,
+	}, nil
+}
@@ -0,0 +1,166 @@
+package openaicompat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"github.com/imroc/req/v3"
+	"go.uber.org/zap"
+)
+
+// Client implements providers.Provider for a backend speaking the OpenAI chat completions API,
+// such as vLLM, a llama.cpp server or Ollama
+type Client struct {
+	name       string
+	httpClient *req.Client
+	models     *providers.ModelCache
+	log        *zap.Logger
+
+	mu      sync.RWMutex
+	healthy bool
+}
+
+// NewClient creates a client for one configured backend
+func NewClient(upstream config.OpenAIUpstream, log *zap.Logger) *Client {
+	httpClient := req.NewClient().
+		SetTimeout(5 * time.Minute).
+		SetBaseURL(strings.TrimSuffix(upstream.BaseURL, "/"))
+	if upstream.APIKey != "" {
+		httpClient.SetCommonBearerAuthToken(upstream.APIKey)
+	}
+
+	c := &Client{
+		name:       upstream.Name,
+		httpClient: httpClient,
+		log:        log,
+	}
+	c.models = providers.NewModelCache(c.fetchModels, log)
+	return c
+}
+
+// Init checks that the backend answers by listing its models. The client reports itself
+// healthy again after any successful request.
+func (c *Client) Init(ctx context.Context) error {
+	models, err := c.models.Refresh(ctx)
+	c.setHealthy(err == nil)
+	if err != nil {
+		return err
+	}
+	c.log.Info("OpenAI-compatible provider initialized", zap.Int("models", len(models)))
+	return nil
+}
+
+func (c *Client) GenerateContent(ctx context.Context, prompt string, options ...providers.GenerateOption) (*providers.Response, error) {
+	stream, err := c.GenerateContentStream(ctx, prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+	return providers.CollectStream(stream)
+}
+
+func (c *Client) GenerateContentStream(ctx context.Context, prompt string, options ...providers.GenerateOption) (<-chan providers.StreamChunk, error) {
+	config := &providers.GenerateConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	turn, err := userMessage(prompt, config.Files)
+	if err != nil {
+		return nil, err
+	}
+	return c.streamChat(ctx, []message{turn}, config)
+}
+
+// StartChat creates a session that sends the whole history with every message
+func (c *Client) StartChat(options ...providers.ChatOption) providers.ChatSession {
+	return providers.NewHistorySession[message](chatBackend{client: c}, options...)
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) GetName() string {
+	return c.name
+}
+
+func (c *Client) IsHealthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.healthy
+}
+
+// ListModels lists the models the backend serves
+func (c *Client) ListModels() []providers.ModelInfo {
+	return c.models.Models()
+}
+
+func (c *Client) setHealthy(healthy bool) {
+	c.mu.Lock()
+	c.healthy = healthy
+	c.mu.Unlock()
+}
+
+// fetchModels lists the models endpoint
+func (c *Client) fetchModels(ctx context.Context) ([]providers.ModelInfo, error) {
+	var list modelList
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		SetSuccessResult(&list).
+		Get("/models")
+	if err != nil {
+		return nil, providers.RequestError(c.name+" list models", err)
+	}
+	if !resp.IsSuccessState() {
+		return nil, c.statusError("list models", resp.StatusCode, resp.Bytes())
+	}
+
+	models := make([]providers.ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		owner := m.OwnedBy
+		if owner == "" {
+			owner = c.name
+		}
+		models = append(models, providers.ModelInfo{
+			ID:       m.ID,
+			Created:  m.Created,
+			OwnedBy:  owner,
+			Provider: c.name,
+		})
+	}
+	return models, nil
+}
+
+// streamChat posts a streamed chat completion and parses the server-sent events as they
+// arrive. The final chunk carries the complete response.
+func (c *Client) streamChat(ctx context.Context, messages []message, config *providers.GenerateConfig) (<-chan providers.StreamChunk, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("%w: %s needs a model", providers.ErrUnknownModel, c.name)
+	}
+
+	resp, err := c.httpClient.R().
+		SetContext(ctx).
+		DisableAutoReadResponse().
+		SetHeader("Accept", "text/event-stream").
+		SetBody(newChatRequest(messages, config)).
+		Post("/chat/completions")
+	if err != nil {
+		return nil, providers.RequestError(c.name+" chat completion", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, c.statusError("chat completion", resp.StatusCode, body)
+	}
+	c.setHealthy(true)
+
+	return providers.StreamEvents(ctx, resp.Body, newAccumulator(config), config.IncludeThoughts, c.name+" chat completion"), nil
+}
@@ -0,0 +1,254 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-bridges/internal/config"
+	"ai-bridges/internal/providers"
+
+	"go.uber.org/zap"
+)
+
+// newTestClient starts a stand-in backend serving handler and returns a client for it
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(config.OpenAIUpstream{Name: "local", BaseURL: server.URL + "/v1/", APIKey: "secret"}, zap.NewNop())
+}
+
+// writeEvents writes server-sent events, one per data value
+func writeEvents(w http.ResponseWriter, data ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, d := range data {
+		fmt.Fprintf(w, "data: %s\n\n", d)
+	}
+}
+
+func TestInitListsModels(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the bearer API key", got)
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"llama3.1","created":1721000000,"owned_by":"meta"},{"id":"qwen3"}]}`)
+	})
+
+	if err := c.Init(context.Background()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if !c.IsHealthy() {
+		t.Error("client not healthy after Init")
+	}
+
+	models := c.ListModels()
+	if len(models) != 2 {
+		t.Fatalf("got %d models, want 2", len(models))
+	}
+	if models[0].ID != "llama3.1" || models[0].OwnedBy != "meta" || models[0].Created != 1721000000 {
+		t.Errorf("unexpected first model %+v", models[0])
+	}
+	if models[1].OwnedBy != "local" || models[1].Provider != "local" {
+		t.Errorf("second model %+v should be owned by the backend name", models[1])
+	}
+}
+
+func TestGenerateContentStream(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var body chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		if body.Model != "llama3.1" || !body.Stream || body.N != 2 {
+			t.Errorf("unexpected request %+v", body)
+		}
+		writeEvents(w,
+			`{"id":"cmpl-1","choices":[{"index":0,"delta":{"reasoning_content":"Thinking."}}]}`,
+			`{"id":"cmpl-1","choices":[{"index":0,"delta":{"content":"Hello"}},{"index":1,"delta":{"content":"Hi"}}]}`,
+			`{"id":"cmpl-1","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"},{"index":1,"delta":{},"finish_reason":"stop"}]}`,
+			`[DONE]`,
+		)
+	})
+
+	stream, err := c.GenerateContentStream(context.Background(), "hello",
+		providers.WithModel("llama3.1"), providers.WithCandidateCount(2), providers.WithThoughts(true))
+	if err != nil {
+		t.Fatalf("GenerateContentStream: %v", err)
+	}
+
+	var text, thoughts strings.Builder
+	var final *providers.Response
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		text.WriteString(chunk.Text)
+		thoughts.WriteString(chunk.Thought)
+		if chunk.Response != nil {
+			final = chunk.Response
+		}
+	}
+
+	if text.String() != "Hello there" || thoughts.String() != "Thinking." {
+		t.Errorf("streamed text %q and thoughts %q", text.String(), thoughts.String())
+	}
+	if final == nil {
+		t.Fatal("no final response")
+	}
+	if final.Text != "Hello there" || final.Thoughts != "Thinking." || final.ResponseID != "cmpl-1" {
+		t.Errorf("unexpected final response %+v", final)
+	}
+	if len(final.Candidates) != 2 || final.Candidates[1].Content != "Hi" || final.Candidates[1].ID != "cmpl-1-1" {
+		t.Errorf("unexpected candidates %+v", final.Candidates)
+	}
+}
+
+func TestGenerateContentFiltered(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w, `{"id":"cmpl-2","choices":[{"index":0,"delta":{},"finish_reason":"content_filter"}]}`, `[DONE]`)
+	})
+
+	_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("llama3.1"))
+	if !errors.Is(err, providers.ErrContentBlocked) {
+		t.Errorf("error %v, want ErrContentBlocked", err)
+	}
+}
+
+func TestGenerateContentStreamError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w,
+			`{"id":"cmpl-3","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+			`{"error":{"message":"model crashed"}}`,
+		)
+	})
+
+	_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("llama3.1"))
+	if !errors.Is(err, providers.ErrUpstreamUnavailable) || !strings.Contains(err.Error(), "model crashed") {
+		t.Errorf("error %v, want ErrUpstreamUnavailable with the event message", err)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusUnauthorized, `{"error":{"message":"invalid key"}}`, providers.ErrAuthExpired},
+		{http.StatusNotFound, `{"error":"model \"nope\" not found"}`, providers.ErrUnknownModel},
+		{http.StatusTooManyRequests, `{"error":{"message":"slow down"}}`, providers.ErrRateLimited},
+		{http.StatusGatewayTimeout, ``, providers.ErrTimeout},
+		{http.StatusServiceUnavailable, `{"error":{"message":"loading model"}}`, providers.ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			c.setHealthy(true)
+
+			_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("nope"))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error %v, want %v", err, tt.want)
+			}
+			if tt.status == http.StatusNotFound && !strings.Contains(err.Error(), `model "nope" not found`) {
+				t.Errorf("error %q should carry the string error of the body", err)
+			}
+			if healthy := c.IsHealthy(); healthy == (tt.want == providers.ErrAuthExpired) {
+				t.Errorf("healthy = %v after %v", healthy, err)
+			}
+		})
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"bad request"}}`)
+	})
+	_, err := c.GenerateContent(context.Background(), "hello", providers.WithModel("llama3.1"))
+	if err == nil || providers.ErrorClass(err) != providers.ClassOther {
+		t.Errorf("error %v, want an unclassified error", err)
+	}
+}
+
+func TestChatSessionResendsHistory(t *testing.T) {
+	var requests []chatRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+			return
+		}
+		requests = append(requests, body)
+		writeEvents(w, fmt.Sprintf(`{"id":"cmpl-%d","choices":[{"index":0,"delta":{"content":"answer %d"}}]}`, len(requests), len(requests)), `[DONE]`)
+	})
+
+	session := c.StartChat(providers.WithChatModel("llama3.1"))
+	for _, text := range []string{"first", "second"} {
+		if _, err := session.SendMessage(context.Background(), text); err != nil {
+			t.Fatalf("SendMessage(%q): %v", text, err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	want := []message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "answer 1"},
+		{Role: "user", Content: "second"},
+	}
+	got := requests[1].Messages
+	if len(got) != len(want) {
+		t.Fatalf("second request sent %d messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Role != want[i].Role || got[i].Content != want[i].Content {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if history := session.GetHistory(); len(history) != 4 || history[3].Content != "answer 2" {
+		t.Errorf("unexpected history %+v", history)
+	}
+	if metadata := session.GetMetadata(); metadata.Model != "llama3.1" || metadata.ResponseID != "cmpl-2" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+}
+
+func TestUserMessageAttachments(t *testing.T) {
+	turn, err := userMessage("describe", []providers.File{
+		{Name: "cat.png", MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+		{Name: "notes.txt", MIMEType: "text/plain", Data: []byte("some notes")},
+	})
+	if err != nil {
+		t.Fatalf("userMessage: %v", err)
+	}
+	parts, ok := turn.Content.([]contentPart)
+	if !ok || len(parts) != 3 {
+		t.Fatalf("unexpected content %+v", turn.Content)
+	}
+	if parts[1].Type != "image_url" || parts[1].ImageURL.URL != "data:image/png;base64,iVBORw==" {
+		t.Errorf("unexpected image part %+v", parts[1])
+	}
+	if parts[2].Type != "text" || parts[2].Text != "some notes" {
+		t.Errorf("unexpected text file part %+v", parts[2])
+	}
+
+	if _, err := userMessage("read", []providers.File{{Name: "doc.pdf", MIMEType: "application/pdf"}}); err == nil {
+		t.Error("a PDF attachment should be rejected")
+	}
+}
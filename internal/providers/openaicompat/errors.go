@@ -0,0 +1,33 @@
+package openaicompat
+
+import (
+	"encoding/json"
+	"errors"
+
+	"ai-bridges/internal/providers"
+)
+
+// statusError classifies a non-200 response. Backends report errors either as
+// {"error": {"message": ...}} or, like Ollama, as {"error": "..."}.
+func (c *Client) statusError(op string, status int, body []byte) error {
+	var detail string
+	var apiErr struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && len(apiErr.Error) > 0 {
+		var object struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(apiErr.Error, &object) == nil {
+			detail = object.Message
+		} else {
+			_ = json.Unmarshal(apiErr.Error, &detail)
+		}
+	}
+
+	err := providers.StatusError(c.name+" "+op, status, detail)
+	if errors.Is(err, providers.ErrAuthExpired) {
+		c.setHealthy(false)
+	}
+	return err
+}
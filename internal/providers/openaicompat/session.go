@@ -0,0 +1,24 @@
+package openaicompat
+
+import (
+	"context"
+
+	"ai-bridges/internal/providers"
+)
+
+// chatBackend sends the conversations of chat sessions as chat completion messages
+type chatBackend struct {
+	client *Client
+}
+
+func (b chatBackend) UserTurn(text string, files []providers.File) (message, error) {
+	return userMessage(text, files)
+}
+
+func (b chatBackend) ModelTurn(text string) message {
+	return message{Role: "assistant", Content: text}
+}
+
+func (b chatBackend) Send(ctx context.Context, turns []message, config *providers.GenerateConfig) (<-chan providers.StreamChunk, error) {
+	return b.client.streamChat(ctx, turns, config)
+}
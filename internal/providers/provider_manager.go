@@ -3,6 +3,9 @@ package providers
 import (
 	"context"
 	"fmt"
+	"strings"
+
+	"ai-bridges/internal/config"
 
 	"go.uber.org/zap"
 )
@@ -10,6 +13,7 @@ import (
 // Factory is a simple factory for creating providers
 type Factory struct {
 	providers map[string]Provider
+	order     []string // names in registration order
 }
 
 // NewFactory creates a new provider factory
@@ -21,6 +25,9 @@ func NewFactory() *Factory {
 
 // Register registers a provider with a name
 func (f *Factory) Register(name string, provider Provider) {
+	if _, ok := f.providers[name]; !ok {
+		f.order = append(f.order, name)
+	}
 	f.providers[name] = provider
 }
 
@@ -29,30 +36,49 @@ func (f *Factory) Get(name string) Provider {
 	return f.providers[name]
 }
 
-// List returns all registered provider names
+// List returns all registered provider names in registration order
 func (f *Factory) List() []string {
-	var names []string
-	for name := range f.providers {
-		names = append(names, name)
-	}
-	return names
+	return append([]string(nil), f.order...)
 }
 
-// ProviderManager manages provider instances
+// mockProvider is the name the mock provider is registered under
+const mockProvider = "mock"
+
+// ProviderManager manages provider instances and routes models to them
 type ProviderManager struct {
-	factory       *Factory
-	log           *zap.Logger
-	selectedType  string
-	selectedName  string
+	factory      *Factory
+	routes       []Route
+	log          *zap.Logger
+	selectedType string
+	selectedName string
 }
 
-// NewProviderManager creates a new provider manager (no concrete imports to avoid cycles)
-func NewProviderManager(log *zap.Logger) *ProviderManager {
+// NewProviderManager creates a new provider manager with the configured model routing table
+// (no concrete imports to avoid cycles)
+func NewProviderManager(cfg *config.Config, log *zap.Logger) (*ProviderManager, error) {
+	routes, err := LoadRoutes(cfg.Models.RoutesFile)
+	if err != nil {
+		return nil, err
+	}
+	// The built-in table gains the mock's own model, and without Gemini accounts the mock
+	// serves the whole table so the bridge answers offline for the same model IDs
+	if cfg.Models.RoutesFile == "" && cfg.Mock.Enabled {
+		if len(cfg.Gemini.Accounts) == 0 {
+			routes = routesServedBy(routes, mockProvider)
+		}
+		routes = append(routes[:len(routes):len(routes)], Route{
+			ID:          mockProvider,
+			Provider:    mockProvider,
+			DisplayName: "Mock",
+			OwnedBy:     mockProvider,
+		})
+	}
 	factory := NewFactory()
 	return &ProviderManager{
 		factory: factory,
+		routes:  routes,
 		log:     log,
-	}
+	}, nil
 }
 
 // Register registers a concrete provider with the manager
@@ -86,6 +112,99 @@ func (pm *ProviderManager) GetProvider(name string) Provider {
 	return pm.factory.Get(name)
 }
 
+// Resolve returns the route serving a public model ID as it applies to the ID: its Model is
+// the upstream model to request. Requests are run through the route with Generate and
+// GenerateStream. A request without a model uses the first model of the routing table.
+func (pm *ProviderManager) Resolve(model string) (Route, error) {
+	model = strings.TrimPrefix(strings.TrimSpace(model), "models/")
+	if model == "" {
+		for _, route := range pm.routes {
+			if !route.isPattern() {
+				model = route.ID
+				break
+			}
+		}
+	}
+
+	route, ok := findRoute(pm.routes, model)
+	if !ok {
+		return Route{}, fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	return route.resolve(model), nil
+}
+
+// ListModels lists the models of the routing table. Exact routes are listed as configured;
+// patterns list the models of their provider that they match.
+func (pm *ProviderManager) ListModels() []ModelInfo {
+	var models []ModelInfo
+	seen := make(map[string]bool)
+	add := func(info ModelInfo) {
+		if !seen[info.ID] {
+			seen[info.ID] = true
+			models = append(models, info)
+		}
+	}
+
+	upstream := make(map[string][]ModelInfo)
+	for _, route := range pm.routes {
+		if !route.isPattern() {
+			add(route.info(ModelInfo{}))
+			continue
+		}
+
+		provider := pm.factory.Get(route.Provider)
+		if provider == nil {
+			continue
+		}
+		if _, ok := upstream[route.Provider]; !ok {
+			upstream[route.Provider] = provider.ListModels()
+		}
+		for _, m := range upstream[route.Provider] {
+			if route.matches(m.ID) {
+				if found, _ := findRoute(pm.routes, m.ID); found.ID == route.ID {
+					add(route.resolve(m.ID).info(m))
+				}
+			}
+		}
+	}
+	return models
+}
+
+// Model describes a public model ID, like ListModels lists it. IDs that only match a pattern
+// must also be listed by the provider: a pattern such as "claude-*" accepts any ID in requests,
+// but made-up IDs are not described as models.
+func (pm *ProviderManager) Model(id string) (ModelInfo, error) {
+	id = strings.TrimPrefix(strings.TrimSpace(id), "models/")
+	route, ok := findRoute(pm.routes, id)
+	if !ok {
+		return ModelInfo{}, fmt.Errorf("%w: %q", ErrUnknownModel, id)
+	}
+	if !route.isPattern() {
+		return route.info(ModelInfo{}), nil
+	}
+
+	provider := pm.factory.Get(route.Provider)
+	if provider == nil {
+		return ModelInfo{}, fmt.Errorf("%w: %q", ErrUnknownModel, id)
+	}
+	for _, m := range provider.ListModels() {
+		if m.ID == id {
+			return route.resolve(id).info(m), nil
+		}
+	}
+	return ModelInfo{}, fmt.Errorf("%w: %q", ErrUnknownModel, id)
+}
+
+// IsHealthy reports whether at least one registered provider can serve requests
+func (pm *ProviderManager) IsHealthy() bool {
+	for _, name := range pm.factory.order {
+		if pm.factory.Get(name).IsHealthy() {
+			return true
+		}
+	}
+	return false
+}
+
 // ListProviders returns all registered provider names
 func (pm *ProviderManager) ListProviders() []string {
 	return pm.factory.List()
@@ -93,12 +212,13 @@ func (pm *ProviderManager) ListProviders() []string {
 
 // InitAllProviders initializes all registered providers (non-blocking - logs warnings on failure)
 func (pm *ProviderManager) InitAllProviders(ctx context.Context) {
-	for name, provider := range pm.factory.providers {
+	for _, name := range pm.factory.order {
+		provider := pm.factory.Get(name)
 		if err := provider.Init(ctx); err != nil {
 			// For Gemini specifically, log a more detailed error since authentication issues are common
 			if name == "gemini" {
-				pm.log.Error("Gemini provider initialization failed - check your cookies in config.yml. Common issues:", 
-					zap.String("provider", name), 
+				pm.log.Error("Gemini provider initialization failed - check your cookies in config.yml. Common issues:",
+					zap.String("provider", name),
 					zap.Error(err),
 					zap.String("tip1", "__Secure-1PSID may be expired"),
 					zap.String("tip2", "__Secure-1PSIDTS may be missing or invalid"),
@@ -114,8 +234,8 @@ func (pm *ProviderManager) InitAllProviders(ctx context.Context) {
 
 // CloseAllProviders closes all registered providers
 func (pm *ProviderManager) CloseAllProviders() error {
-	for name, provider := range pm.factory.providers {
-		if err := provider.Close(); err != nil {
+	for _, name := range pm.factory.order {
+		if err := pm.factory.Get(name).Close(); err != nil {
 			pm.log.Error("Failed to close provider", zap.String("provider", name), zap.Error(err))
 		}
 	}
@@ -0,0 +1,165 @@
+package providers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRoutes has an exact ID inside a pattern, a pass-through pattern and an aliasing pattern
+var testRoutes = []Route{
+	{ID: "gemini-pro", Provider: "web", Model: "unspecified", DisplayName: "Default"},
+	{ID: "claude-3-opus", Provider: "api", Model: "gemini-2.5-pro"},
+	{ID: "gem/*", Provider: "web"},
+	{ID: "claude-*", Provider: "web", Model: "unspecified"},
+	{ID: "claude-3-*", Provider: "api"},
+}
+
+func newRoutingManager() *ProviderManager {
+	web := &fakeProvider{name: "web", models: []ModelInfo{
+		{ID: "gem/coding-partner", Name: "Coding partner"},
+		{ID: "claude-3-opus"},
+		{ID: "claude-sonnet-4"},
+		{ID: "gemini-2.5-flash"},
+	}}
+	api := &fakeProvider{name: "api"}
+	return newTestManager(testRoutes, web, api)
+}
+
+func TestResolve(t *testing.T) {
+	pm := newRoutingManager()
+	tests := []struct {
+		id           string
+		wantProvider string
+		wantModel    string
+	}{
+		{"gemini-pro", "web", "unspecified"},
+		{"models/gemini-pro", "web", "unspecified"},
+		{"", "web", "unspecified"},                          // the first exact route
+		{"claude-3-opus", "api", "gemini-2.5-pro"},          // exact IDs win over patterns
+		{"claude-3-haiku", "web", "unspecified"},            // patterns are tried in table order
+		{"gem/coding-partner", "web", "gem/coding-partner"}, // pass-through patterns keep the ID
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			route, err := pm.Resolve(tt.id)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if route.Provider != tt.wantProvider || route.Model != tt.wantModel {
+				t.Errorf("resolved to %s/%s, want %s/%s", route.Provider, route.Model, tt.wantProvider, tt.wantModel)
+			}
+		})
+	}
+
+	for _, id := range []string{"gpt-4", "gemini", "gem"} {
+		if _, err := pm.Resolve(id); !errors.Is(err, ErrUnknownModel) {
+			t.Errorf("Resolve(%q) error %v, want ErrUnknownModel", id, err)
+		}
+	}
+}
+
+func TestModel(t *testing.T) {
+	pm := newRoutingManager()
+	tests := []struct {
+		id      string
+		wantErr bool
+		want    string // name of the described model
+	}{
+		{id: "gemini-pro", want: "Default"},
+		{id: "gem/coding-partner", want: "Coding partner"},
+		{id: "claude-sonnet-4"}, // listed by the provider of the aliasing pattern
+		{id: "gem/unknown", wantErr: true},
+		{id: "claude-made-up", wantErr: true}, // accepted in requests, but not a listed model
+		{id: "gpt-4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			info, err := pm.Model(tt.id)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnknownModel) {
+					t.Errorf("error %v, want ErrUnknownModel", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Model: %v", err)
+			}
+			if info.ID != tt.id || info.Name != tt.want {
+				t.Errorf("described as %+v", info)
+			}
+		})
+	}
+}
+
+func TestListModels(t *testing.T) {
+	var ids []string
+	for _, m := range newRoutingManager().ListModels() {
+		ids = append(ids, m.ID)
+	}
+	// claude-3-opus is listed once, by its exact route; gemini-2.5-flash matches no route
+	want := "gemini-pro,claude-3-opus,gem/coding-partner,claude-sonnet-4"
+	if got := strings.Join(ids, ","); got != want {
+		t.Errorf("listed %s, want %s", got, want)
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	routes, err := LoadRoutes("")
+	if err != nil || len(routes) != len(DefaultRoutes) {
+		t.Fatalf("LoadRoutes(\"\") = %d routes, %v; want the default table", len(routes), err)
+	}
+	if err := validateRoutes(DefaultRoutes); err != nil {
+		t.Errorf("default routes are invalid: %v", err)
+	}
+	if route, ok := findRoute(DefaultRoutes, "gpt-4o"); !ok || route.ID != "gpt-4o" {
+		t.Error("the gpt-4o alias of earlier versions is missing")
+	}
+
+	dir := t.TempDir()
+	write := func(content string) string {
+		filename := filepath.Join(dir, "routes.json")
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	routes, err = LoadRoutes(write(`[{"id": "llama*", "provider": "openai", "fallbacks": [{"provider": "gemini"}], "fallback_on": ["timeout"]}]`))
+	if err != nil {
+		t.Fatalf("LoadRoutes: %v", err)
+	}
+	if len(routes) != 1 || routes[0].Fallbacks[0].Provider != "gemini" || routes[0].FallbackOn[0] != ClassTimeout {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid JSON", `[{"id": "a", "provider": "b"`, "parse model routes"},
+		{"not an array", `{"id": "a", "provider": "b"}`, "parse model routes"},
+		{"empty table", `[]`, "no routes defined"},
+		{"missing provider", `[{"id": "a"}]`, "id and provider are required"},
+		{"missing ID", `[{"provider": "b"}]`, "id and provider are required"},
+		{"duplicate ID", `[{"id": "a", "provider": "b"}, {"id": "a", "provider": "c"}]`, `route 2: duplicate id "a"`},
+		{"bad pattern", `[{"id": "claude-[", "provider": "b"}]`, "invalid pattern"},
+		{"fallback without provider", `[{"id": "a", "provider": "b", "fallbacks": [{"model": "c"}]}]`, "fallbacks need a provider"},
+		{"unknown error class", `[{"id": "a", "provider": "b", "fallback_on": ["sometimes"]}]`, `cannot fall back on "sometimes"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRoutes(write(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadRoutes(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "read model routes") {
+		t.Errorf("missing file error %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Route maps a public model ID, or a glob pattern of IDs, to the provider and upstream
+// model serving it. Routes with an exact ID are listed by the models endpoints; patterns
+// list the provider's own models they match.
+type Route struct {
+	ID           string   `json:"id"`              // public ID or pattern such as "claude-*" (path.Match syntax)
+	Provider     string   `json:"provider"`        // name the provider is registered under
+	Model        string   `json:"model,omitempty"` // upstream model; empty sends the requested ID unchanged
+	DisplayName  string   `json:"display_name,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	OwnedBy      string   `json:"owned_by,omitempty"`
+	Created      int64    `json:"created,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	Account    int      `json:"account,omitempty"`     // account of a pooled provider; 0 lets the provider pick
+	Fallbacks  []Target `json:"fallbacks,omitempty"`   // tried in order when the route's provider fails
+	FallbackOn []string `json:"fallback_on,omitempty"` // error classes that move on to the next fallback
+}
+
+// Target is one step of a route's fallback chain: a provider, or one account of a pooled provider
+type Target struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`   // upstream model; defaults to the route's
+	Account  int    `json:"account,omitempty"` // account of a pooled provider; 0 lets the provider pick
+}
+
+// defaultFallbackOn are the error classes that trigger a fallback when a route sets no rules:
+// rate limits and upstream failures. Content blocks and auth errors are returned as they are.
+var defaultFallbackOn = []string{ClassRateLimited, ClassUnavailable, ClassTimeout, ClassParseFailure}
+
+// fallbackClasses lists the error classes fallback rules may name. Content blocks are left
+// out on purpose: another upstream must not be asked to answer a refused prompt.
+var fallbackClasses = map[string]bool{
+	ClassAuthExpired:  true,
+	ClassUnknownModel: true,
+	ClassRateLimited:  true,
+	ClassUnavailable:  true,
+	ClassParseFailure: true,
+	ClassTimeout:      true,
+	ClassOther:        true,
+}
+
+// isPattern reports whether the route matches several IDs
+func (r Route) isPattern() bool {
+	return strings.ContainsAny(r.ID, "*?[")
+}
+
+// matches reports whether the route serves a public model ID
+func (r Route) matches(id string) bool {
+	if !r.isPattern() {
+		return r.ID == id
+	}
+	ok, _ := path.Match(r.ID, id)
+	return ok
+}
+
+// resolve returns the route as it applies to a requested ID
+func (r Route) resolve(id string) Route {
+	r.ID = id
+	if r.Model == "" {
+		r.Model = id
+	}
+	return r
+}
+
+// info describes the route as a model, filling in what the provider knows about it
+func (r Route) info(upstream ModelInfo) ModelInfo {
+	info := upstream
+	info.ID = r.ID
+	info.Provider = r.Provider
+	if r.DisplayName != "" {
+		info.Name = r.DisplayName
+	}
+	if r.Description != "" {
+		info.Description = r.Description
+	}
+	if r.OwnedBy != "" {
+		info.OwnedBy = r.OwnedBy
+	}
+	if info.OwnedBy == "" {
+		info.OwnedBy = r.Provider
+	}
+	if r.Created != 0 {
+		info.Created = r.Created
+	}
+	if len(r.Capabilities) > 0 {
+		info.Capabilities = r.Capabilities
+	}
+	return info
+}
+
+// chain returns the targets of a resolved route in the order they are tried
+func (r Route) chain() []Target {
+	chain := []Target{{Provider: r.Provider, Model: r.Model, Account: r.Account}}
+	for _, target := range r.Fallbacks {
+		if target.Model == "" {
+			target.Model = r.Model
+		}
+		chain = append(chain, target)
+	}
+	return chain
+}
+
+// fallsBackOn reports whether an error moves the route on to its next fallback
+func (r Route) fallsBackOn(err error) bool {
+	rules := r.FallbackOn
+	if len(rules) == 0 {
+		rules = defaultFallbackOn
+	}
+	return slices.Contains(rules, ErrorClass(err))
+}
+
+// LoadRoutes reads a routing table from a JSON file holding an array of routes.
+// An empty filename returns DefaultRoutes.
+func LoadRoutes(filename string) ([]Route, error) {
+	if filename == "" {
+		return DefaultRoutes, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read model routes: %w", err)
+	}
+	var routes []Route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("parse model routes %s: %w", filename, err)
+	}
+	if err := validateRoutes(routes); err != nil {
+		return nil, fmt.Errorf("invalid model routes %s: %w", filename, err)
+	}
+	return routes, nil
+}
+
+// validateRoutes checks that every route names an ID and a provider and that patterns compile
+func validateRoutes(routes []Route) error {
+	if len(routes) == 0 {
+		return errors.New("no routes defined")
+	}
+	seen := make(map[string]bool)
+	for i, route := range routes {
+		if route.ID == "" || route.Provider == "" {
+			return fmt.Errorf("route %d: id and provider are required", i+1)
+		}
+		if seen[route.ID] {
+			return fmt.Errorf("route %d: duplicate id %q", i+1, route.ID)
+		}
+		seen[route.ID] = true
+		if _, err := path.Match(route.ID, ""); err != nil {
+			return fmt.Errorf("route %d: invalid pattern %q: %w", i+1, route.ID, err)
+		}
+		for _, target := range route.Fallbacks {
+			if target.Provider == "" {
+				return fmt.Errorf("route %d: fallbacks need a provider", i+1)
+			}
+		}
+		for _, class := range route.FallbackOn {
+			if !fallbackClasses[class] {
+				return fmt.Errorf("route %d: cannot fall back on %q", i+1, class)
+			}
+		}
+	}
+	return nil
+}
+
+// routesServedBy returns a copy of a routing table whose routes all go to one provider.
+// Pass-through routes keep sending the requested ID.
+func routesServedBy(routes []Route, provider string) []Route {
+	served := make([]Route, len(routes))
+	for i, route := range routes {
+		route.Provider = provider
+		route.Fallbacks = nil
+		served[i] = route
+	}
+	return served
+}
+
+// findRoute returns the route serving a public model ID. Exact IDs win over patterns,
+// and patterns are tried in table order.
+func findRoute(routes []Route, id string) (Route, bool) {
+	for _, route := range routes {
+		if !route.isPattern() && route.ID == id {
+			return route, true
+		}
+	}
+	for _, route := range routes {
+		if route.isPattern() && route.matches(id) {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
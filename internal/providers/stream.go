@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// EventDecoder decodes the server-sent events of a streamed API response
+type EventDecoder interface {
+	// Decode merges the data of one event and returns the text and thoughts it adds to the
+	// first candidate. It returns io.EOF for the end-of-stream marker of APIs that send one.
+	Decode(data string) (StreamChunk, error)
+
+	// Response builds the complete response once every event was decoded
+	Response() (*Response, error)
+}
+
+// CollectStream drains a response stream and returns its final response
+func CollectStream(chunks <-chan StreamChunk) (*Response, error) {
+	var final *Response
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			return nil, chunk.Err
+		}
+		if chunk.Response != nil {
+			final = chunk.Response
+		}
+	}
+
+	if final == nil {
+		return nil, errors.New("stream ended without a response")
+	}
+	return final, nil
+}
+
+// StreamEvents reads the server-sent events of body in the background and streams what decoder
+// finds in them, thoughts only when includeThoughts is set. The final chunk carries the complete
+// response. op names the request in read errors; body is closed once the stream ends.
+func StreamEvents(ctx context.Context, body io.ReadCloser, decoder EventDecoder, includeThoughts bool, op string) <-chan StreamChunk {
+	chunks := make(chan StreamChunk)
+	go func() {
+		defer body.Close()
+		defer close(chunks)
+		readEvents(ctx, body, chunks, decoder, includeThoughts, op)
+	}()
+	return chunks
+}
+
+func readEvents(ctx context.Context, body io.Reader, chunks chan<- StreamChunk, decoder EventDecoder, includeThoughts bool, op string) {
+	send := func(chunk StreamChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		delta, err := decoder.Decode(strings.TrimSpace(data))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			send(StreamChunk{Err: err})
+			return
+		}
+
+		if !includeThoughts {
+			delta.Thought = ""
+		}
+		if delta.Text != "" || delta.Thought != "" {
+			if !send(StreamChunk{Text: delta.Text, Thought: delta.Thought}) {
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		send(StreamChunk{Err: RequestError(op, err)})
+		return
+	}
+
+	response, err := decoder.Response()
+	if err != nil {
+		send(StreamChunk{Err: err})
+		return
+	}
+	send(StreamChunk{Response: response})
+}
@@ -20,33 +20,40 @@ import (
 )
 
 type Server struct {
-	app            *fiber.App
-	geminiHandler  *handlers.GeminiHandler
-	openaiHandler  *handlers.OpenAIHandler
-	claudeHandler  *handlers.ClaudeHandler
-	cfg            *config.Config
-	log            *zap.Logger
-	appMu          sync.Mutex
+	app           *fiber.App
+	geminiHandler *handlers.GeminiHandler
+	openaiHandler *handlers.OpenAIHandler
+	claudeHandler *handlers.ClaudeHandler
+	adminHandler  *handlers.AdminHandler
+	cfg           *config.Config
+	log           *zap.Logger
+	appMu         sync.Mutex
 }
 
-func New(lc fx.Lifecycle, geminiHandler *handlers.GeminiHandler, openaiHandler *handlers.OpenAIHandler, claudeHandler *handlers.ClaudeHandler, cfg *config.Config, log *zap.Logger) (*Server, error) {
+func New(lc fx.Lifecycle, geminiHandler *handlers.GeminiHandler, openaiHandler *handlers.OpenAIHandler, claudeHandler *handlers.ClaudeHandler, adminHandler *handlers.AdminHandler, cfg *config.Config, log *zap.Logger) (*Server, error) {
 	// Inject logger into handlers
 	geminiHandler.SetLogger(log)
 	openaiHandler.SetLogger(log)
 	claudeHandler.SetLogger(log)
+	adminHandler.SetLogger(log)
+
+	if !adminHandler.Enabled() {
+		log.Info("Admin API disabled, set ADMIN_TOKEN to enable it")
+	}
 
 	server := &Server{
 		geminiHandler: geminiHandler,
 		openaiHandler: openaiHandler,
 		claudeHandler: claudeHandler,
+		adminHandler:  adminHandler,
 		cfg:           cfg,
 		log:           log,
 	}
 
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			app := buildApp(log, geminiHandler, openaiHandler, claudeHandler)
-			
+			app := buildApp(log, geminiHandler, openaiHandler, claudeHandler, adminHandler)
+
 			server.appMu.Lock()
 			server.app = app
 			server.appMu.Unlock()
@@ -62,7 +69,7 @@ func New(lc fx.Lifecycle, geminiHandler *handlers.GeminiHandler, openaiHandler *
 		OnStop: func(ctx context.Context) error {
 			server.appMu.Lock()
 			defer server.appMu.Unlock()
-			
+
 			if server.app != nil {
 				return server.app.ShutdownWithContext(ctx)
 			}
@@ -80,36 +87,36 @@ func (s *Server) startServerWithFallback() error {
 		s.log.Info("Server started on port", zap.String("port", port))
 		return nil
 	}
-	
+
 	s.log.Warn("Failed to bind to configured port, trying alternatives", zap.String("port", port))
-	
+
 	// Try alternative ports
 	alternativePorts := []string{"3001", "3002", "3003", "3004", "3005", "8080", "8081", "8082", "9000", "9001"}
-	
+
 	for _, altPort := range alternativePorts {
 		s.log.Info("Attempting to start server on alternative port", zap.String("port", altPort))
-		
+
 		// Create new app instance for each attempt
-		altApp := buildApp(s.log, s.geminiHandler, s.openaiHandler, s.claudeHandler)
-		
+		altApp := buildApp(s.log, s.geminiHandler, s.openaiHandler, s.claudeHandler, s.adminHandler)
+
 		if err := altApp.Listen(":" + altPort); err == nil {
 			s.log.Info("Server started successfully on alternative port", zap.String("port", altPort))
-			
+
 			// Update server app reference
 			s.appMu.Lock()
 			s.app = altApp
 			s.appMu.Unlock()
-			
+
 			return nil
 		}
 		s.log.Debug("Failed to bind to alternative port", zap.String("port", altPort))
 	}
-	
+
 	return fmt.Errorf("failed to start server on any available port")
 }
 
 // buildApp creates and configures a Fiber app with all middleware and routes
-func buildApp(log *zap.Logger, geminiHandler *handlers.GeminiHandler, openaiHandler *handlers.OpenAIHandler, claudeHandler *handlers.ClaudeHandler) *fiber.App {
+func buildApp(log *zap.Logger, geminiHandler *handlers.GeminiHandler, openaiHandler *handlers.OpenAIHandler, claudeHandler *handlers.ClaudeHandler, adminHandler *handlers.AdminHandler) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName: "AI Bridges API",
 	})
@@ -119,7 +126,7 @@ func buildApp(log *zap.Logger, geminiHandler *handlers.GeminiHandler, openaiHand
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Requested-With, x-api-key, anthropic-version",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS, PATCH",
 	}))
-	
+
 	app.Use(logger.NewMiddleware(log))
 	app.Use(recover.New())
 
@@ -138,6 +145,11 @@ func buildApp(log *zap.Logger, geminiHandler *handlers.GeminiHandler, openaiHand
 	claudeV1 := claudeGroup.Group("/v1")
 	controllers.NewClaudeController(claudeHandler).Register(claudeV1)
 
+	// --- Admin routes (only with ADMIN_TOKEN set) ---
+	if adminHandler.Enabled() {
+		controllers.NewAdminController(adminHandler).Register(app.Group("/admin"))
+	}
+
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -157,8 +169,8 @@ func buildApp(log *zap.Logger, geminiHandler *handlers.GeminiHandler, openaiHand
 				},
 			},
 		}
-		
-		// If degraded, we still return 200 because the server is running, 
+
+		// If degraded, we still return 200 because the server is running,
 		// but providing details for monitoring.
 		// If you want Docker to restart the container on Gemini failure, change to 503.
 		return c.JSON(health)